@@ -1,9 +1,12 @@
 package client
 
 import (
+	"context"
 	"github.com/go-errors/errors"
 	"github.com/m1/smap/crawler"
+	"net/http"
 	"net/url"
+	"time"
 )
 
 const (
@@ -32,6 +35,176 @@ type Config struct {
 	// UserAgent is the user agent that the crawler will use
 	// defaults to `smap-v0.0.1`
 	UserAgent string
+
+	// MaxDepth is the maximum click distance from the seed
+	// url to crawl pages at, 0 means no limit
+	MaxDepth int
+
+	// MaxPages is the maximum number of pages to crawl,
+	// 0 means no limit
+	MaxPages int
+
+	// MaxDuration is the maximum time a crawl can run for,
+	// 0 means no limit
+	MaxDuration time.Duration
+
+	// Include are the scope rules that links need to match
+	// one of to be crawled, e.g. `crawler.PathPrefix("/docs/")`
+	Include []crawler.ScopeRule
+
+	// Exclude are the scope rules that stop links from being
+	// crawled, excluded links are still kept as edges and
+	// marked as out of scope
+	Exclude []crawler.ScopeRule
+
+	// StayInSeedPath keeps the crawl inside the paths of the
+	// seed urls, e.g. a seed of `https://example.com/docs/`
+	// only crawls the pages under `/docs/`
+	StayInSeedPath bool
+
+	// TrailingSlash is the normalization policy for the
+	// trailing slash on link paths, defaults to keeping
+	// the paths as they are linked
+	TrailingSlash crawler.TrailingSlash
+
+	// LowercasePath lowercases link paths when canonicalizing
+	// them, for sites with case insensitive paths
+	LowercasePath bool
+
+	// SortQuery sorts the query parameters of the links
+	// by key when canonicalizing them
+	SortQuery bool
+
+	// StripQuery removes the query from the links
+	// when canonicalizing them
+	StripQuery bool
+
+	// QueryAllow are the only query parameters kept when
+	// canonicalizing the links, e.g. `page`
+	QueryAllow []string
+
+	// QueryDeny are the query parameters removed when
+	// canonicalizing the links, e.g. `utm_*` or `sessionid`
+	QueryDeny []string
+
+	// KeepErrorPages keeps the pages that couldn't be crawled,
+	// e.g. 404s, in the sitemap along with the links to them
+	KeepErrorPages bool
+
+	// RecordHeaders are the response headers recorded for each
+	// page, defaults to `crawler.DefaultRecordHeaders`
+	RecordHeaders []string
+
+	// LongRedirectChain is the number of redirects a page can
+	// go through before its chain is warned about, defaults to 3
+	LongRedirectChain int
+
+	// Sitemaps also seeds the crawl from the sitemaps listed
+	// in the robots.txt, or `/sitemap.xml` if there are none
+	Sitemaps bool
+
+	// RequestsPerSecond is the maximum rate of requests to the
+	// host, 0 means no limit. The robots.txt `Crawl-delay` is
+	// used instead if it's stricter
+	RequestsPerSecond float64
+
+	// MaxConnsPerHost is the maximum number of requests to the
+	// host in flight at once, 0 means no limit
+	MaxConnsPerHost int
+
+	// AdaptiveThrottle lowers the number of workers when the
+	// host responds with 429s or 503s or slows down, raising
+	// it back up to `MaxWorkers` once it's healthy
+	AdaptiveThrottle bool
+
+	// Retry is how pages that failed with a transient
+	// error are retried, by default they aren't
+	Retry crawler.RetryPolicy
+
+	// HTTPClient is the client used for the requests, when
+	// set the transport settings below are ignored
+	HTTPClient *http.Client
+
+	// Transport is the transport used for the requests,
+	// when set the transport settings below are ignored
+	Transport http.RoundTripper
+
+	// RequestTimeout is the total time a request can take,
+	// including reading the body, defaults to 10s
+	RequestTimeout time.Duration
+
+	// ConnectTimeout is the time connecting to the host
+	// can take, defaults to 30s
+	ConnectTimeout time.Duration
+
+	// TLSHandshakeTimeout is the time the tls handshake
+	// can take, defaults to 10s
+	TLSHandshakeTimeout time.Duration
+
+	// ResponseHeaderTimeout is the time to wait for the
+	// response headers once the request is sent
+	ResponseHeaderTimeout time.Duration
+
+	// MaxIdleConnsPerHost is the number of connections to
+	// the host kept open for reuse, defaults to `MaxWorkers`
+	MaxIdleConnsPerHost int
+
+	// Proxy is the http proxy the requests go through,
+	// defaults to the proxy environment variables
+	Proxy *url.URL
+
+	// CAFile is a pem bundle of the certificate authorities
+	// to trust along with the system ones
+	CAFile string
+
+	// CertFile and KeyFile are the pem client certificate
+	// and key, for hosts that need mutual tls
+	CertFile string
+	KeyFile  string
+
+	// InsecureSkipVerify turns off checking the tls
+	// certificates of the host
+	InsecureSkipVerify bool
+
+	// Headers are extra headers sent with every request
+	Headers http.Header
+
+	// Auth is the basic auth or bearer token sent with
+	// the requests to the seed host
+	Auth crawler.Auth
+
+	// CookieJar is the jar the cookies are kept in, a new
+	// one is used if not set. `CookieFile` is a Netscape
+	// cookies.txt file that's loaded into the jar
+	CookieJar  http.CookieJar
+	CookieFile string
+
+	// Login is a form posted before the crawl starts,
+	// e.g. to get a session cookie
+	Login *crawler.LoginForm
+
+	// FollowNoFollow follows the links marked nofollow, by
+	// their rel or the meta robots of the page, which are
+	// otherwise kept but not crawled
+	FollowNoFollow bool
+
+	// LinkRules are the rules for extracting the links from
+	// the pages, defaults to `crawler.DefaultLinkRules`
+	LinkRules []crawler.LinkRule
+
+	// CheckAssets also requests the links of the rules that
+	// aren't followed, e.g. images and scripts, to check they
+	// work without crawling them as pages
+	CheckAssets bool
+
+	// DetectSoftNotFound requests a random url on each host to
+	// learn what its not found page looks like, flagging pages
+	// like it, or with a not found title, as `Page.SoftNotFound`
+	DetectSoftNotFound bool
+
+	// OnProgress is called with the progress of the
+	// crawl each time a page has been crawled
+	OnProgress func(crawler.Progress)
 }
 
 // New passes back a new client, populates the config
@@ -45,6 +218,43 @@ func New(config *Config) (*Client, error) {
 		return nil, errors.New("maxworkers must be above 0")
 	}
 
+	if config.MaxDepth < 0 {
+		return nil, errors.New("maxdepth can't be below 0")
+	}
+
+	if config.MaxPages < 0 {
+		return nil, errors.New("maxpages can't be below 0")
+	}
+
+	if config.MaxDuration < 0 {
+		return nil, errors.New("maxduration can't be below 0")
+	}
+
+	if config.RequestsPerSecond < 0 {
+		return nil, errors.New("requestspersecond can't be below 0")
+	}
+
+	if config.MaxConnsPerHost < 0 {
+		return nil, errors.New("maxconnsperhost can't be below 0")
+	}
+
+	if config.Retry.MaxAttempts < 0 {
+		return nil, errors.New("retry maxattempts can't be below 0")
+	}
+
+	if config.Retry.BaseDelay < 0 || config.Retry.MaxDelay < 0 {
+		return nil, errors.New("retry delays can't be below 0")
+	}
+
+	if config.RequestTimeout < 0 || config.ConnectTimeout < 0 ||
+		config.TLSHandshakeTimeout < 0 || config.ResponseHeaderTimeout < 0 {
+		return nil, errors.New("timeouts can't be below 0")
+	}
+
+	if config.MaxIdleConnsPerHost < 0 {
+		return nil, errors.New("maxidleconnsperhost can't be below 0")
+	}
+
 	if config.UserAgent == "" {
 		config.UserAgent = defaultUserAgent
 	}
@@ -57,15 +267,95 @@ func New(config *Config) (*Client, error) {
 // Crawl starts the crawling of the url and passes back the
 // sitemap
 func (c *Client) Crawl(url *url.URL) (crawler.SiteMap, error) {
-	if url.Path != "/" && url.Path != "" {
-		return nil, errors.New("url should be the base url")
+	return c.CrawlContext(context.Background(), url)
+}
+
+// CrawlContext starts the crawling of the url and passes back
+// the sitemap, stopping once the context is done. If the crawl
+// was stopped early the sitemap crawled so far is passed back
+// along with `crawler.ErrCancelled`, if a crawl limit was hit
+// the sitemap is passed back along with `crawler.ErrTruncated`
+func (c *Client) CrawlContext(ctx context.Context, url *url.URL) (crawler.SiteMap, error) {
+	return c.CrawlSeeds(ctx, url)
+}
+
+// CrawlSeeds starts the crawling at each of the seed urls and
+// passes back a single sitemap for them, the seeds need to be
+// on the same host. Stops early the same as `CrawlContext`
+func (c *Client) CrawlSeeds(ctx context.Context, urls ...*url.URL) (crawler.SiteMap, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("at least one url is needed")
+	}
+
+	var seeds []url.URL
+	for _, u := range urls {
+		if u.Host != urls[0].Host {
+			return nil, errors.New("urls should be on the same host")
+		}
+		seeds = append(seeds, *u)
+	}
+
+	cr := crawler.NewWithSeeds(seeds, c.crawlerConfig())
+	err := cr.RunContext(ctx)
+	if err == crawler.ErrCancelled || err == crawler.ErrTruncated {
+		return cr.SiteMap, err
 	}
 
-	cr := crawler.New(*url, c.Config.IgnoreRobotsTxt, c.Config.MaxWorkers, c.Config.UserAgent)
-	err := cr.Run()
 	if err != nil {
 		return nil, err
 	}
 
 	return cr.SiteMap, nil
 }
+
+// crawlerConfig maps the client config to the
+// config for the crawler
+func (c *Client) crawlerConfig() crawler.Config {
+	return crawler.Config{
+		MaxWorkers:            c.Config.MaxWorkers,
+		IgnoreRobotsTxt:       c.Config.IgnoreRobotsTxt,
+		UserAgent:             c.Config.UserAgent,
+		MaxDepth:              c.Config.MaxDepth,
+		MaxPages:              c.Config.MaxPages,
+		MaxDuration:           c.Config.MaxDuration,
+		Include:               c.Config.Include,
+		Exclude:               c.Config.Exclude,
+		StayInSeedPath:        c.Config.StayInSeedPath,
+		TrailingSlash:         c.Config.TrailingSlash,
+		LowercasePath:         c.Config.LowercasePath,
+		SortQuery:             c.Config.SortQuery,
+		StripQuery:            c.Config.StripQuery,
+		QueryAllow:            c.Config.QueryAllow,
+		QueryDeny:             c.Config.QueryDeny,
+		KeepErrorPages:        c.Config.KeepErrorPages,
+		RecordHeaders:         c.Config.RecordHeaders,
+		LongRedirectChain:     c.Config.LongRedirectChain,
+		Sitemaps:              c.Config.Sitemaps,
+		RequestsPerSecond:     c.Config.RequestsPerSecond,
+		MaxConnsPerHost:       c.Config.MaxConnsPerHost,
+		AdaptiveThrottle:      c.Config.AdaptiveThrottle,
+		Retry:                 c.Config.Retry,
+		HTTPClient:            c.Config.HTTPClient,
+		Transport:             c.Config.Transport,
+		RequestTimeout:        c.Config.RequestTimeout,
+		ConnectTimeout:        c.Config.ConnectTimeout,
+		TLSHandshakeTimeout:   c.Config.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: c.Config.ResponseHeaderTimeout,
+		MaxIdleConnsPerHost:   c.Config.MaxIdleConnsPerHost,
+		Proxy:                 c.Config.Proxy,
+		CAFile:                c.Config.CAFile,
+		CertFile:              c.Config.CertFile,
+		KeyFile:               c.Config.KeyFile,
+		InsecureSkipVerify:    c.Config.InsecureSkipVerify,
+		Headers:               c.Config.Headers,
+		Auth:                  c.Config.Auth,
+		CookieJar:             c.Config.CookieJar,
+		CookieFile:            c.Config.CookieFile,
+		Login:                 c.Config.Login,
+		FollowNoFollow:        c.Config.FollowNoFollow,
+		LinkRules:             c.Config.LinkRules,
+		CheckAssets:           c.Config.CheckAssets,
+		DetectSoftNotFound:    c.Config.DetectSoftNotFound,
+		OnProgress:            c.Config.OnProgress,
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/m1/smap/crawler"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+func newAuditCmd() *cobra.Command {
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Audits of a site.",
+	}
+
+	auditCmd.AddCommand(&cobra.Command{
+		Run:   auditSitemap,
+		Use:   "sitemap [url...]",
+		Short: "Compares the sitemaps of a site with its links, exiting non-zero if any issues are found.",
+		Args:  cobra.MinimumNArgs(1),
+	})
+
+	auditCmd.AddCommand(&cobra.Command{
+		Run:   auditCanonicals,
+		Use:   "canonicals [url...]",
+		Short: "Checks the canonical and hreflang tags of a site, exiting non-zero if any issues are found.",
+		Args:  cobra.MinimumNArgs(1),
+	})
+
+	auditCmd.AddCommand(&cobra.Command{
+		Run:   auditDuplicates,
+		Use:   "duplicates [url...]",
+		Short: "Lists the pages with duplicate or near duplicate text, exiting non-zero if any are found.",
+		Args:  cobra.MinimumNArgs(1),
+	})
+
+	return auditCmd
+}
+
+func auditSitemap(_ *cobra.Command, args []string) {
+	useSitemaps = true
+	keepErrors = true
+	siteMap, err := crawl(args)
+
+	audit := siteMap.AuditSitemap()
+	printSitemapAudit(audit)
+	finish(err)
+
+	if len(audit.Orphans)+len(audit.Unlisted)+len(audit.Problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+// printSitemapAudit prints the orphaned, unlisted and
+// problem urls of the audit, or the audit as json
+func printSitemapAudit(audit crawler.SitemapAudit) {
+	if jsonOutput() {
+		js, err := json.Marshal(audit)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(string(js))
+		return
+	}
+
+	fmt.Println(fmt.Sprintf("Orphans, in the sitemaps but not linked to (%d):", len(audit.Orphans)))
+	for _, u := range audit.Orphans {
+		fmt.Println(fmt.Sprintf("\t%s", u))
+	}
+
+	fmt.Println(fmt.Sprintf("Unlisted, linked to but not in the sitemaps (%d):", len(audit.Unlisted)))
+	for _, u := range audit.Unlisted {
+		fmt.Println(fmt.Sprintf("\t%s", u))
+	}
+
+	fmt.Println(fmt.Sprintf("Problems, in the sitemaps but can't be crawled (%d):", len(audit.Problems)))
+	for _, p := range audit.Problems {
+		switch p.Issue {
+		case crawler.SitemapRedirect:
+			fmt.Println(fmt.Sprintf("\t%s (%s %d -> %s)", p.URL, p.Issue, p.StatusCode, p.RedirectsTo))
+		case crawler.SitemapBlocked:
+			fmt.Println(fmt.Sprintf("\t%s (%s, %s)", p.URL, p.Issue, p.Error))
+		default:
+			status := p.Error
+			if p.StatusCode != 0 {
+				status = fmt.Sprintf("%d", p.StatusCode)
+			}
+			fmt.Println(fmt.Sprintf("\t%s (%s %s, %s)", p.URL, p.Issue, status, p.ErrorCategory))
+		}
+	}
+}
+
+func auditDuplicates(_ *cobra.Command, args []string) {
+	opts := crawler.DuplicateOptions{Threshold: dupThreshold}
+	if err := opts.Validate(); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	siteMap, err := crawl(args)
+
+	clusters, dupErr := siteMap.Duplicates(opts)
+	if dupErr != nil {
+		fmt.Println(dupErr.Error())
+		os.Exit(1)
+	}
+	printDuplicates(clusters)
+	finish(err)
+
+	if len(clusters) > 0 {
+		os.Exit(1)
+	}
+}
+
+// printDuplicates prints each cluster of duplicate pages
+// with its canonical urls, or the clusters as json
+func printDuplicates(clusters []crawler.DuplicateCluster) {
+	if jsonOutput() {
+		if clusters == nil {
+			clusters = []crawler.DuplicateCluster{}
+		}
+
+		js, err := json.Marshal(clusters)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(string(js))
+		return
+	}
+
+	for _, c := range clusters {
+		kind := "exact duplicates"
+		if !c.Exact {
+			kind = fmt.Sprintf("near duplicates, %.0f%% similar", c.Similarity*100)
+		}
+		fmt.Println(fmt.Sprintf("%d pages, %s:", len(c.URLs), kind))
+		for _, u := range c.URLs {
+			fmt.Println(fmt.Sprintf("\t%s", u))
+		}
+
+		canonical := "consistent"
+		if !c.CanonicalConsistent {
+			canonical = "inconsistent"
+		}
+		for i, u := range c.Canonicals {
+			if u == "" {
+				c.Canonicals[i] = "(none)"
+			}
+		}
+		fmt.Println(fmt.Sprintf("\tcanonical %s: %s", canonical, strings.Join(c.Canonicals, ", ")))
+	}
+
+	fmt.Println(fmt.Sprintf("%d duplicate clusters found", len(clusters)))
+}
+
+func auditCanonicals(_ *cobra.Command, args []string) {
+	keepErrors = true
+	siteMap, err := crawl(args)
+
+	findings := siteMap.AuditCanonicals()
+	printCanonicalFindings(findings)
+	finish(err)
+
+	if len(findings) > 0 {
+		os.Exit(1)
+	}
+}
+
+// printCanonicalFindings prints the findings grouped
+// by page with their severity, or as json
+func printCanonicalFindings(findings []crawler.CanonicalFinding) {
+	if jsonOutput() {
+		if findings == nil {
+			findings = []crawler.CanonicalFinding{}
+		}
+
+		js, err := json.Marshal(findings)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(string(js))
+		return
+	}
+
+	errorCount := 0
+	for i, f := range findings {
+		if i == 0 || findings[i-1].URL != f.URL {
+			fmt.Println(f.URL)
+		}
+		if f.Severity == crawler.SeverityError {
+			errorCount++
+		}
+
+		line := fmt.Sprintf("\t[%s] %s", f.Severity, f.Issue)
+		if f.Target != "" {
+			line += fmt.Sprintf(" -> %s", f.Target)
+		}
+		if f.Detail != "" {
+			line += fmt.Sprintf(" (%s)", f.Detail)
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Println(fmt.Sprintf("%d issues found, %d errors and %d warnings", len(findings), errorCount, len(findings)-errorCount))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/m1/smap/crawler"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+func newLinksCmd() *cobra.Command {
+	linksCmd := &cobra.Command{
+		Use:   "links",
+		Short: "Link reports for a site.",
+	}
+
+	linksCmd.AddCommand(&cobra.Command{
+		Run:   linksCheck,
+		Use:   "check [url...]",
+		Short: "Lists the broken internal links on a site, and soft 404s with --soft-404, exiting non-zero if any are found. Links to rate limited pages are listed as unknown.",
+		Args:  cobra.MinimumNArgs(1),
+	})
+
+	return linksCmd
+}
+
+func linksCheck(_ *cobra.Command, args []string) {
+	keepErrors = true
+	siteMap, err := crawl(args)
+
+	broken := siteMap.BrokenLinks()
+	printBrokenLinks(broken)
+	finish(err)
+
+	for _, l := range broken {
+		if !l.Unknown {
+			os.Exit(1)
+		}
+	}
+}
+
+// printBrokenLinks prints the broken links grouped by
+// their target, or as json
+func printBrokenLinks(broken []crawler.BrokenLink) {
+	if jsonOutput() {
+		if broken == nil {
+			broken = []crawler.BrokenLink{}
+		}
+
+		js, err := json.Marshal(broken)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(string(js))
+		return
+	}
+
+	for i, l := range broken {
+		if i == 0 || broken[i-1].Target != l.Target {
+			status := l.Error
+			if l.StatusCode != 0 {
+				status = fmt.Sprintf("%d", l.StatusCode)
+			}
+
+			category := string(l.ErrorCategory)
+			switch {
+			case l.SoftNotFound:
+				category = "soft 404"
+			case l.Unknown:
+				category = "unknown, rate limited"
+			}
+
+			fmt.Println(fmt.Sprintf("%s (%s, %s), linked from %d pages:", l.Target, status, category, l.LinkedFromCount))
+		}
+
+		fmt.Println(fmt.Sprintf("\t%s %q", l.Source, l.AnchorText))
+	}
+
+	unknown := 0
+	for _, l := range broken {
+		if l.Unknown {
+			unknown++
+		}
+	}
+
+	fmt.Println(fmt.Sprintf("%d broken links found", len(broken)-unknown))
+	if unknown > 0 {
+		fmt.Println(fmt.Sprintf("%d links to rate limited pages couldn't be checked", unknown))
+	}
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/briandowns/spinner"
 	"github.com/m1/smap"
+	"github.com/m1/smap/crawler"
 	"github.com/spf13/cobra"
+	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 )
 
@@ -15,15 +21,60 @@ var (
 	rootCmd         *cobra.Command
 	verbose         bool
 	jsonPrint       bool
+	jsonReport      bool
+	format          string
+	outDir          string
+	gzipOutput      bool
+	sitemapBase     string
 	maxWorkers      int
 	ignoreRobotsTxt bool
 	userAgent       string
+	maxDepth        int
+	maxPages        int
+	maxDuration     time.Duration
+	include         []string
+	exclude         []string
+	stayInPath      bool
+	trailingSlash   string
+	lowercasePath   bool
+	sortQuery       bool
+	stripQuery      bool
+	queryAllow      []string
+	queryDeny       []string
+	keepErrors      bool
+	longRedirects   int
+	useSitemaps     bool
+	requestsPerSec  float64
+	maxConnsPerHost int
+	adaptive        bool
+	maxAttempts     int
+	retryDelay      time.Duration
+	requestTimeout  time.Duration
+	connectTimeout  time.Duration
+	tlsTimeout      time.Duration
+	headerTimeout   time.Duration
+	maxIdleConns    int
+	proxy           string
+	caFile          string
+	certFile        string
+	keyFile         string
+	insecure        bool
+	headers         []string
+	cookieFile      string
+	auth            string
+	loginURL        string
+	loginFields     []string
+	followNoFollow  bool
+	skipNoIndex     bool
+	checkAssets     bool
+	dupThreshold    float64
+	softNotFound    bool
 )
 
 func main() {
 	rootCmd = &cobra.Command{
 		Run:   smap,
-		Use:   "smap [url]",
+		Use:   "smap [url...]",
 		Short: "smap is a site-mapping engine.",
 		Long:  "smap is a site-mapping engine written in Go.",
 		Args:  cobra.MinimumNArgs(1),
@@ -31,62 +82,377 @@ func main() {
 
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose printing")
 	rootCmd.PersistentFlags().BoolVar(&jsonPrint, "json", false, "json output")
+	rootCmd.PersistentFlags().BoolVar(&jsonReport, "json-report", false, "Wrap the json output in an object with the pages, redirect chains and urls blocked by the robots.txt")
+	rootCmd.PersistentFlags().StringVar(&format, "format", "text", "Output format: text, json, csv or xml")
+	rootCmd.PersistentFlags().StringVar(&outDir, "out", ".", "Directory to write the xml sitemap files to")
+	rootCmd.PersistentFlags().BoolVar(&gzipOutput, "gzip", false, "Gzip the xml sitemap files")
+	rootCmd.PersistentFlags().StringVar(&sitemapBase, "sitemap-base", "", "Url the xml sitemap files are served from, defaults to the root of the first url")
 	rootCmd.PersistentFlags().IntVarP(&maxWorkers, "workers", "w", 50, "How many workers to use")
 	rootCmd.PersistentFlags().BoolVar(&ignoreRobotsTxt, "robots", false, "Ignores robots.txt")
 	rootCmd.PersistentFlags().StringVarP(&userAgent, "user-agent", "u", "", "User agent to use for the crawler")
+	rootCmd.PersistentFlags().IntVar(&maxDepth, "max-depth", 0, "Maximum click depth from the url to crawl, 0 for no limit")
+	rootCmd.PersistentFlags().IntVar(&maxPages, "max-pages", 0, "Maximum number of pages to crawl, 0 for no limit")
+	rootCmd.PersistentFlags().DurationVar(&maxDuration, "max-duration", 0, "Maximum time to crawl for, e.g. 10m, 0 for no limit")
+	rootCmd.PersistentFlags().StringSliceVar(&include, "include", nil, "Only crawl paths matching these rules, path prefixes or glob:/regex: patterns")
+	rootCmd.PersistentFlags().StringSliceVar(&exclude, "exclude", nil, "Don't crawl paths matching these rules, path prefixes or glob:/regex: patterns")
+	rootCmd.PersistentFlags().BoolVar(&stayInPath, "stay-in-path", false, "Only crawl pages under the paths of the urls")
+	rootCmd.PersistentFlags().StringVar(&trailingSlash, "trailing-slash", "keep", "Trailing slash policy for link paths: keep, add or remove")
+	rootCmd.PersistentFlags().BoolVar(&lowercasePath, "lowercase-path", false, "Lowercase link paths, for sites with case insensitive paths")
+	rootCmd.PersistentFlags().BoolVar(&sortQuery, "sort-query", false, "Sort the query parameters of links")
+	rootCmd.PersistentFlags().BoolVar(&stripQuery, "strip-query", false, "Remove the query from links")
+	rootCmd.PersistentFlags().StringSliceVar(&queryAllow, "query-allow", nil, "Only keep these query parameters, e.g. page")
+	rootCmd.PersistentFlags().StringSliceVar(&queryDeny, "query-deny", nil, "Remove these query parameters, e.g. utm_*,sessionid")
+	rootCmd.PersistentFlags().BoolVar(&keepErrors, "keep-errors", false, "Keep pages that couldn't be crawled, e.g. 404s, in the output")
+	rootCmd.PersistentFlags().IntVar(&longRedirects, "long-redirect-chain", 3, "Warn about redirect chains with more hops than this")
+	rootCmd.PersistentFlags().Float64Var(&requestsPerSec, "rps", 0, "Maximum requests per second to the host, 0 for no limit, the robots.txt Crawl-delay is used if stricter")
+	rootCmd.PersistentFlags().IntVar(&maxConnsPerHost, "max-conns-per-host", 0, "Maximum requests to the host in flight at once, 0 for no limit")
+	rootCmd.PersistentFlags().BoolVar(&adaptive, "adaptive", false, "Lower the workers when the host responds with 429s or 503s or slows down")
+	rootCmd.PersistentFlags().IntVar(&maxAttempts, "max-attempts", 1, "Times to try pages that fail with timeouts, connection errors, 429s or 5xxs")
+	rootCmd.PersistentFlags().DurationVar(&retryDelay, "retry-delay", 500*time.Millisecond, "Backoff before the first retry, doubled for each retry after")
+	rootCmd.PersistentFlags().DurationVar(&requestTimeout, "timeout", 10*time.Second, "Total time a request can take")
+	rootCmd.PersistentFlags().DurationVar(&connectTimeout, "connect-timeout", 30*time.Second, "Time connecting to the host can take")
+	rootCmd.PersistentFlags().DurationVar(&tlsTimeout, "tls-timeout", 10*time.Second, "Time the tls handshake can take")
+	rootCmd.PersistentFlags().DurationVar(&headerTimeout, "header-timeout", 0, "Time to wait for the response headers, 0 for no limit")
+	rootCmd.PersistentFlags().IntVar(&maxIdleConns, "max-idle-conns", 0, "Connections to the host kept open for reuse, defaults to the workers")
+	rootCmd.PersistentFlags().StringVar(&proxy, "proxy", "", "Http proxy to use, defaults to the proxy environment variables")
+	rootCmd.PersistentFlags().StringVar(&caFile, "ca-file", "", "Pem bundle of certificate authorities to trust")
+	rootCmd.PersistentFlags().StringVar(&certFile, "cert", "", "Pem client certificate, for mutual tls")
+	rootCmd.PersistentFlags().StringVar(&keyFile, "key", "", "Pem client certificate key, for mutual tls")
+	rootCmd.PersistentFlags().BoolVar(&insecure, "insecure", false, "Don't check the tls certificates of the host")
+	rootCmd.PersistentFlags().StringArrayVarP(&headers, "header", "H", nil, "Extra header to send with every request, e.g. \"X-Env: staging\"")
+	rootCmd.PersistentFlags().StringVar(&cookieFile, "cookie-file", "", "Netscape cookies.txt file to load cookies from")
+	rootCmd.PersistentFlags().StringVar(&auth, "auth", "", "Credentials for the host, user:password for basic auth or bearer:token")
+	rootCmd.PersistentFlags().StringVar(&loginURL, "login-url", "", "Url of a login form to post before crawling")
+	rootCmd.PersistentFlags().StringArrayVar(&loginFields, "login-field", nil, "Field of the login form, e.g. username=me")
+	rootCmd.PersistentFlags().BoolVar(&followNoFollow, "follow-nofollow", false, "Crawl links marked nofollow by their rel or the meta robots of the page")
+	rootCmd.PersistentFlags().BoolVar(&checkAssets, "check-assets", false, "Also check the images, scripts, stylesheets and media linked to, without crawling them")
+	rootCmd.PersistentFlags().BoolVar(&skipNoIndex, "skip-noindex", false, "Leave pages marked noindex out of the text, json and csv output")
+	rootCmd.PersistentFlags().BoolVar(&softNotFound, "soft-404", false, "Request a random url to learn the not found page of the host, flagging pages like it as soft 404s")
+	rootCmd.PersistentFlags().Float64Var(&dupThreshold, "duplicate-threshold", crawler.DefaultDuplicateThreshold, "Similarity above 0 and up to 1 for pages to be near duplicates in the duplicates audit, 1 for exact duplicates only")
+	rootCmd.PersistentFlags().BoolVar(&useSitemaps, "sitemaps", false, "Also crawl the urls in the sitemaps of the robots.txt, or /sitemap.xml")
+
+	rootCmd.AddCommand(newLinksCmd())
+	rootCmd.AddCommand(newAuditCmd())
 
 	rootCmd.Execute()
 }
 
 func smap(_ *cobra.Command, args []string) {
-	u, err := url.Parse(args[0])
+	if format != "text" && format != "json" && format != "csv" && format != "xml" {
+		fmt.Println("format needs to be text, json, csv or xml")
+		os.Exit(1)
+	}
+
+	siteMap, err := crawl(args)
+	if format == "xml" {
+		writeXML(siteMap, args[0])
+	} else {
+		if skipNoIndex {
+			siteMap = siteMap.WithoutNoIndex()
+		}
+
+		if format == "csv" {
+			if err := siteMap.WriteCSV(os.Stdout); err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+		} else {
+			printSiteMap(siteMap)
+		}
+	}
+	finish(err)
+}
+
+// crawl sets up the client from the flags and crawls the
+// urls, exiting if the crawl fails. A sitemap is passed back
+// along with the error if the crawl was stopped early
+func crawl(args []string) (crawler.SiteMap, error) {
+	var seeds []*url.URL
+	for _, arg := range args {
+		u, err := url.Parse(arg)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+		if u.Scheme != "http" && u.Scheme != "https" {
+			fmt.Println("needs to http or https")
+			os.Exit(1)
+		}
+
+		seeds = append(seeds, u)
+	}
+
+	slashPolicy, err := crawler.ParseTrailingSlash(trailingSlash)
 	if err != nil {
 		fmt.Println(err.Error())
 		os.Exit(1)
 	}
 
-	if u.Path != "" && u.Path != "/" {
-		fmt.Println("needs to be base url")
+	includeRules, err := parseScopeRules(include)
+	if err != nil {
+		fmt.Println(err.Error())
 		os.Exit(1)
 	}
 
-	if u.Scheme != "http" && u.Scheme != "https" {
-		fmt.Println("needs to http or https")
+	excludeRules, err := parseScopeRules(exclude)
+	if err != nil {
+		fmt.Println(err.Error())
 		os.Exit(1)
 	}
 
-	c, err := client.New(&client.Config{
-		MaxWorkers:      maxWorkers,
-		IgnoreRobotsTxt: ignoreRobotsTxt,
-		UserAgent:       userAgent,
-	})
+	var proxyURL *url.URL
+	if proxy != "" {
+		proxyURL, err = url.Parse(proxy)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	header, err := parseHeaders(headers)
 	if err != nil {
 		fmt.Println(err.Error())
 		os.Exit(1)
 	}
 
+	var credentials crawler.Auth
+	if auth != "" {
+		credentials, err = crawler.ParseAuth(auth)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	var login *crawler.LoginForm
+	if loginURL != "" {
+		login, err = parseLogin(loginURL, loginFields)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	}
+
 	var spin *spinner.Spinner
+	var onProgress func(crawler.Progress)
 	if verbose {
 		spin = spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-		spin.Suffix = fmt.Sprintf(" Crawling %s", u.String())
+		spin.Suffix = fmt.Sprintf(" Crawling %s", strings.Join(args, ", "))
 		spin.Writer = os.Stderr
-		spin.Start()
+
+		onProgress = func(p crawler.Progress) {
+			spin.Lock()
+			spin.Suffix = fmt.Sprintf(" Crawling %s (%d crawled, %d queued, %d/%d workers)",
+				strings.Join(args, ", "), p.Crawled, p.Queued, p.Concurrency, p.MaxConcurrency)
+			spin.Unlock()
+		}
 	}
 
-	siteMap, err := c.Crawl(u)
+	c, err := client.New(&client.Config{
+		MaxWorkers:        maxWorkers,
+		IgnoreRobotsTxt:   ignoreRobotsTxt,
+		UserAgent:         userAgent,
+		MaxDepth:          maxDepth,
+		MaxPages:          maxPages,
+		MaxDuration:       maxDuration,
+		Include:           includeRules,
+		Exclude:           excludeRules,
+		StayInSeedPath:    stayInPath,
+		TrailingSlash:     slashPolicy,
+		LowercasePath:     lowercasePath,
+		SortQuery:         sortQuery,
+		StripQuery:        stripQuery,
+		QueryAllow:        queryAllow,
+		QueryDeny:         queryDeny,
+		KeepErrorPages:    keepErrors,
+		LongRedirectChain: longRedirects,
+		Sitemaps:          useSitemaps,
+		RequestsPerSecond: requestsPerSec,
+		MaxConnsPerHost:   maxConnsPerHost,
+		AdaptiveThrottle:  adaptive,
+		Retry: crawler.RetryPolicy{
+			MaxAttempts: maxAttempts,
+			BaseDelay:   retryDelay,
+		},
+		RequestTimeout:        requestTimeout,
+		ConnectTimeout:        connectTimeout,
+		TLSHandshakeTimeout:   tlsTimeout,
+		ResponseHeaderTimeout: headerTimeout,
+		MaxIdleConnsPerHost:   maxIdleConns,
+		Proxy:                 proxyURL,
+		CAFile:                caFile,
+		CertFile:              certFile,
+		KeyFile:               keyFile,
+		InsecureSkipVerify:    insecure,
+		Headers:               header,
+		Auth:                  credentials,
+		CookieFile:            cookieFile,
+		Login:                 login,
+		FollowNoFollow:        followNoFollow,
+		CheckAssets:           checkAssets,
+		DetectSoftNotFound:    softNotFound,
+		OnProgress:            onProgress,
+	})
 	if err != nil {
 		fmt.Println(err.Error())
 		os.Exit(1)
 	}
 
+	if spin != nil {
+		spin.Start()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	siteMap, err := c.CrawlSeeds(ctx, seeds...)
+	stop()
 	if verbose && spin != nil {
 		spin.Stop()
 	}
 
-	if !jsonPrint {
+	if err != nil && err != crawler.ErrCancelled && err != crawler.ErrTruncated {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	return siteMap, err
+}
+
+// finish reports if the crawl was stopped early,
+// exiting if it was cancelled
+func finish(err error) {
+	if err == crawler.ErrTruncated {
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
+
+	if err == crawler.ErrCancelled {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+// parseHeaders parses the header flag values
+// in the `Name: value` format
+func parseHeaders(values []string) (http.Header, error) {
+	header := http.Header{}
+	for _, v := range values {
+		i := strings.Index(v, ":")
+		if i < 1 {
+			return nil, fmt.Errorf("header %q needs to be Name: value", v)
+		}
+		header.Add(strings.TrimSpace(v[:i]), strings.TrimSpace(v[i+1:]))
+	}
+	return header, nil
+}
+
+// parseLogin parses the login form flag values,
+// the fields are in the `name=value` format
+func parseLogin(u string, fields []string) (*crawler.LoginForm, error) {
+	login := &crawler.LoginForm{URL: u, Fields: url.Values{}}
+	for _, f := range fields {
+		i := strings.Index(f, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("login field %q needs to be name=value", f)
+		}
+		login.Fields.Add(f[:i], f[i+1:])
+	}
+	return login, nil
+}
+
+// jsonOutput checks if json output was
+// asked for by any of the flags
+func jsonOutput() bool {
+	return jsonPrint || jsonReport || format == "json"
+}
+
+// parseScopeRules parses the include/exclude flag values
+func parseScopeRules(values []string) ([]crawler.ScopeRule, error) {
+	var rules []crawler.ScopeRule
+	for _, v := range values {
+		rule, err := crawler.ParseScopeRule(v)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// output is the json output of the crawl with --json-report,
+// the pages along with the redirect chains found on the site
+// and the urls blocked by the robots.txt
+type output struct {
+	Pages     crawler.SiteMap         `json:"pages"`
+	Redirects []crawler.RedirectChain `json:"redirects"`
+	Blocked   []crawler.BlockedURL    `json:"blocked"`
+}
+
+// writeXML writes the sitemap as sitemaps.org xml files
+// to the out dir, printing the files written
+func writeXML(siteMap crawler.SiteMap, seed string) {
+	base := sitemapBase
+	if base == "" {
+		base = seed
+	}
+
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	if sitemapBase == "" {
+		baseURL = &url.URL{Scheme: baseURL.Scheme, Host: baseURL.Host, Path: "/"}
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	files, err := siteMap.WriteXML(outDir, crawler.XMLOptions{
+		BaseURL: baseURL,
+		Gzip:    gzipOutput,
+	})
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	for _, f := range files {
+		fmt.Println(f)
+	}
+}
+
+// printSiteMap prints the crawled sitemap as text, unless
+// only json is wanted, and then as json
+func printSiteMap(siteMap crawler.SiteMap) {
+	if !jsonOutput() {
 		for _, v := range siteMap {
+			fmt.Println(fmt.Sprintf("URL: %s", v.URL.String()))
 			fmt.Println(fmt.Sprintf("Path: %s", v.URL.Path))
+			fmt.Println(fmt.Sprintf("Status: %d", v.Response.StatusCode))
+			if v.Info.Title != "" {
+				fmt.Println(fmt.Sprintf("Title: %s", v.Info.Title))
+			}
+			if v.Asset {
+				fmt.Println("Asset: true")
+			}
+			if v.SoftNotFound {
+				fmt.Println("Soft 404: true")
+			}
+			if v.Attempts > 1 {
+				fmt.Println(fmt.Sprintf("Attempts: %d", v.Attempts))
+			}
+			if v.Response.ErrorCategory != "" {
+				fmt.Println(fmt.Sprintf("Error: %s (%s)", v.Response.Error, v.Response.ErrorCategory))
+			}
 			fmt.Println(fmt.Sprintf("Redirect: %t", v.IsRedirect))
+			fmt.Println(fmt.Sprintf("Discovered By: %s", v.DiscoveredBy))
+			if v.NoIndex || v.NoFollow {
+				fmt.Println(fmt.Sprintf("NoIndex: %t", v.NoIndex))
+				fmt.Println(fmt.Sprintf("NoFollow: %t", v.NoFollow))
+			}
 
 			redirectUrl := "null"
 			if v.IsRedirect {
@@ -97,19 +463,66 @@ func smap(_ *cobra.Command, args []string) {
 
 			fmt.Println("Links:")
 			for _, l := range v.Links {
-				fmt.Println(fmt.Sprintf("\t%s", l.URL.Path))
+				if l.OutOfScope {
+					fmt.Println(fmt.Sprintf("\t%s (out of scope)", l.URL.String()))
+					continue
+				}
+				if l.NoFollow {
+					fmt.Println(fmt.Sprintf("\t%s (nofollow)", l.URL.String()))
+					continue
+				}
+				if l.Asset {
+					fmt.Println(fmt.Sprintf("\t%s (%s)", l.URL.String(), l.Type))
+					continue
+				}
+				fmt.Println(fmt.Sprintf("\t%s", l.URL.String()))
+			}
+
+			if len(v.BlockedLinks) > 0 {
+				fmt.Println("Blocked By Robots.txt:")
+				for _, b := range v.BlockedLinks {
+					fmt.Println(fmt.Sprintf("\t%s (%s)", b.URL, b.Rule))
+				}
 			}
 
 			fmt.Println("Linked From:")
 			for _, l := range v.LinkedFrom {
-				fmt.Println(fmt.Sprintf("\t%s", l.URL.Path))
+				fmt.Println(fmt.Sprintf("\t%s", l.URL.String()))
+			}
+
+			if len(v.Variants) > 0 {
+				fmt.Println("Variants:")
+				for _, variant := range v.Variants {
+					fmt.Println(fmt.Sprintf("\t%s", variant))
+				}
+			}
+
+			if len(v.RedirectChain) > 0 {
+				fmt.Println("Redirect Chain:")
+				for _, hop := range v.RedirectChain {
+					fmt.Println(fmt.Sprintf("\t%d %s -> %s", hop.StatusCode, hop.URL, hop.Location))
+				}
+			}
+
+			for _, w := range v.RedirectWarnings {
+				fmt.Println(fmt.Sprintf("Redirect Warning: %s", w))
 			}
 
 			fmt.Println()
 		}
 	}
 
-	js, err := json.Marshal(&siteMap)
+	var js []byte
+	var err error
+	if jsonReport {
+		js, err = json.Marshal(&output{
+			Pages:     siteMap,
+			Redirects: siteMap.Redirects(),
+			Blocked:   siteMap.Blocked(),
+		})
+	} else {
+		js, err = json.Marshal(&siteMap)
+	}
 	if err != nil {
 		fmt.Println(err.Error())
 		os.Exit(1)
@@ -0,0 +1,104 @@
+package crawler
+
+import (
+	"sort"
+)
+
+// SitemapIssue is the problem with a url
+// listed in the sitemaps
+type SitemapIssue string
+
+// The problems a url listed in the sitemaps can have,
+// sitemaps should only list the canonical urls of pages
+// that can be crawled
+const (
+	SitemapRedirect SitemapIssue = "redirect"
+	SitemapError    SitemapIssue = "error"
+	SitemapBlocked  SitemapIssue = "blocked"
+)
+
+// SitemapProblem is a url listed in the sitemaps
+// that redirects, errors or is blocked
+type SitemapProblem struct {
+	URL           string        `json:"url"`
+	Issue         SitemapIssue  `json:"issue"`
+	StatusCode    int           `json:"status_code,omitempty"`
+	ErrorCategory ErrorCategory `json:"error_category,omitempty"`
+	Error         string        `json:"error,omitempty"`
+	RedirectsTo   string        `json:"redirects_to,omitempty"`
+}
+
+// SitemapAudit compares the sitemaps of the
+// site with the pages found by following links
+type SitemapAudit struct {
+	// Orphans are the crawlable pages listed in the sitemaps
+	// that no crawled page links to, other than the seeds
+	Orphans []string `json:"orphans"`
+
+	// Unlisted are the crawlable pages that were
+	// found by links but aren't in the sitemaps
+	Unlisted []string `json:"unlisted"`
+
+	// Problems are the urls listed in the sitemaps that
+	// redirect, had errors or are blocked by the robots.txt
+	Problems []SitemapProblem `json:"problems"`
+}
+
+// inSitemap checks if the page was listed in the sitemaps
+func (p *Page) inSitemap() bool {
+	return p.DiscoveredBy == DiscoverySitemap || p.DiscoveredBy == DiscoveryBoth
+}
+
+// AuditSitemap compares the urls listed in the sitemaps with the
+// link graph, each list is sorted by url. The crawl needs to be
+// seeded from the sitemaps and keep the error pages for this to
+// be complete, see `Config.Sitemaps` and `Config.KeepErrorPages`
+func (s SiteMap) AuditSitemap() SitemapAudit {
+	audit := SitemapAudit{
+		Orphans:  []string{},
+		Unlisted: []string{},
+		Problems: []SitemapProblem{},
+	}
+
+	for key, page := range s {
+		switch {
+		case page.inSitemap() && page.IsRedirect:
+			status := page.Response.StatusCode
+			if len(page.RedirectChain) > 0 {
+				status = page.RedirectChain[0].StatusCode
+			}
+
+			audit.Problems = append(audit.Problems, SitemapProblem{
+				URL:         key,
+				Issue:       SitemapRedirect,
+				StatusCode:  status,
+				RedirectsTo: page.RedirectsTo.String(),
+			})
+		case page.inSitemap() && page.Response.ErrorCategory != "":
+			issue := SitemapError
+			if page.Response.ErrorCategory == ErrorRobots {
+				issue = SitemapBlocked
+			}
+
+			audit.Problems = append(audit.Problems, SitemapProblem{
+				URL:           key,
+				Issue:         issue,
+				StatusCode:    page.Response.StatusCode,
+				ErrorCategory: page.Response.ErrorCategory,
+				Error:         page.Response.Error,
+			})
+		case page.DiscoveredBy == DiscoverySitemap && !page.seed:
+			audit.Orphans = append(audit.Orphans, key)
+		case !page.inSitemap() && page.indexable():
+			audit.Unlisted = append(audit.Unlisted, key)
+		}
+	}
+
+	sort.Strings(audit.Orphans)
+	sort.Strings(audit.Unlisted)
+	sort.Slice(audit.Problems, func(i, j int) bool {
+		return audit.Problems[i].URL < audit.Problems[j].URL
+	})
+
+	return audit
+}
@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestSiteMap_AuditSitemap(t *testing.T) {
+	s := test.NewSitemapServer()
+	s.WithSitemapRobots()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:     1,
+		UserAgent:      "test-robot",
+		Sitemaps:       true,
+		KeepErrorPages: true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, SitemapAudit{
+		Orphans:  []string{s.URL + "/unlinked/"},
+		Unlisted: []string{s.URL + "/about/"},
+		Problems: []SitemapProblem{
+			{
+				URL:           s.URL + "/gone/",
+				Issue:         SitemapError,
+				StatusCode:    http.StatusNotFound,
+				ErrorCategory: ErrorClient,
+				Error:         "404 Not Found",
+			},
+			{
+				URL:         s.URL + "/moved/",
+				Issue:       SitemapRedirect,
+				StatusCode:  http.StatusMovedPermanently,
+				RedirectsTo: s.URL + "/linked/",
+			},
+			{
+				URL:           s.URL + "/private/",
+				Issue:         SitemapBlocked,
+				ErrorCategory: ErrorRobots,
+				Error:         "blocked by robots.txt: Disallow: /private/",
+			},
+		},
+	}, crawler.SiteMap.AuditSitemap())
+
+	assert.Empty(t, crawler.SiteMap.BrokenLinks())
+}
+
+func TestSiteMap_AuditSitemap_Seed(t *testing.T) {
+	s := test.NewSitemapServer()
+	s.Start()
+	defer s.Close()
+
+	u := *s.Url
+	u.Path = "/unlinked/"
+	crawler := NewWithConfig(u, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		Sitemaps:        true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, DiscoverySitemap, crawler.SiteMap[s.URL+"/unlinked/"].DiscoveredBy)
+	assert.Empty(t, crawler.SiteMap.AuditSitemap().Orphans)
+}
+
+func TestSiteMap_AuditSitemap_NoSitemap(t *testing.T) {
+	s := test.NewServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := New(*s.Url, true, 1, "")
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	audit := crawler.SiteMap.AuditSitemap()
+	assert.Empty(t, audit.Orphans)
+	assert.Empty(t, audit.Problems)
+	assert.Len(t, audit.Unlisted, len(crawler.SiteMap))
+}
@@ -0,0 +1,211 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"golang.org/x/net/publicsuffix"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	authBearerPrefix = "bearer:"
+	headerAuth       = "Authorization"
+
+	// cookieFileHTTPOnly is the prefix curl gives the
+	// domain of http only cookies in cookies.txt files
+	cookieFileHTTPOnly = "#HttpOnly_"
+	cookieFileFields   = 7
+)
+
+// Auth is the credentials sent with the requests to the seed
+// host, either a basic auth username and password or a
+// bearer token
+type Auth struct {
+	Username string
+	Password string
+	Token    string
+}
+
+// ParseAuth parses the auth from the cli format, either
+// `user:password` for basic auth or `bearer:token`
+func ParseAuth(s string) (Auth, error) {
+	if strings.HasPrefix(strings.ToLower(s), authBearerPrefix) {
+		return Auth{Token: s[len(authBearerPrefix):]}, nil
+	}
+
+	i := strings.Index(s, ":")
+	if i < 1 {
+		return Auth{}, fmt.Errorf("auth %q needs to be user:password or bearer:token", s)
+	}
+
+	return Auth{Username: s[:i], Password: s[i+1:]}, nil
+}
+
+// apply sets the authorization header of the request
+func (a Auth) apply(r *http.Request) {
+	switch {
+	case a.Token != "":
+		r.Header.Set(headerAuth, "Bearer "+a.Token)
+	case a.Username != "":
+		r.SetBasicAuth(a.Username, a.Password)
+	}
+}
+
+// LoginForm is a form that's posted before the crawl starts,
+// e.g. a login page, so that the session cookie it sets is
+// sent with the rest of the requests
+type LoginForm struct {
+	// URL is where the form is posted to, relative
+	// urls are resolved against the seed url
+	URL string
+
+	// Fields are the values of the form
+	Fields url.Values
+}
+
+// newRequest builds a request with the user agent and extra
+// headers set, the auth is only sent to the seed host
+func (c *Crawler) newRequest(ctx context.Context, method string, u string, body io.Reader) (*http.Request, error) {
+	request, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range c.config.Headers {
+		for _, v := range values {
+			request.Header.Add(name, v)
+		}
+	}
+	request.Header.Set(headerUserAgent, c.config.UserAgent)
+
+	if request.URL.Host == c.url.Host {
+		c.config.Auth.apply(request)
+	}
+
+	return request.WithContext(ctx), nil
+}
+
+// login posts the login form, the cookies it sets are
+// kept in the cookie jar of the http client
+func (c *Crawler) login(ctx context.Context) error {
+	loginURL, err := c.url.Parse(c.config.Login.URL)
+	if err != nil {
+		return err
+	}
+
+	request, err := c.newRequest(ctx, http.MethodPost, loginURL.String(), strings.NewReader(c.config.Login.Fields.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set(headerContentType, "application/x-www-form-urlencoded")
+
+	client, err := c.httpClient()
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("login to %s failed: %s", loginURL, resp.Status)
+	}
+
+	return nil
+}
+
+// newCookieJar passes back the cookie jar of the config, or
+// a new one, with the cookies of the cookie file loaded in
+func (c Config) newCookieJar() (http.CookieJar, error) {
+	jar := c.CookieJar
+	if jar == nil {
+		var err error
+		jar, err = cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.CookieFile == "" {
+		return jar, nil
+	}
+
+	f, err := os.Open(c.CookieFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := loadCookies(jar, f); err != nil {
+		return nil, fmt.Errorf("%s: %s", c.CookieFile, err.Error())
+	}
+
+	return jar, nil
+}
+
+// loadCookies reads the cookies from a Netscape cookies.txt
+// file into the jar, expired cookies are skipped
+func loadCookies(jar http.CookieJar, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		httpOnly := strings.HasPrefix(text, cookieFileHTTPOnly)
+		text = strings.TrimPrefix(text, cookieFileHTTPOnly)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) != cookieFileFields {
+			return fmt.Errorf("line %d: needs %d tab separated fields", line, cookieFileFields)
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid expiry %q", line, fields[4])
+		}
+
+		cookie := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+		}
+
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+			if cookie.Expires.Before(time.Now()) {
+				continue
+			}
+		}
+
+		host := strings.TrimPrefix(fields[0], ".")
+		if strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = host
+		}
+
+		scheme := schemeHTTP
+		if cookie.Secure {
+			scheme = schemeHTTPS
+		}
+
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: host, Path: cookie.Path}, []*http.Cookie{cookie})
+	}
+
+	return scanner.Err()
+}
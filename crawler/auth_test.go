@@ -0,0 +1,195 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAuth(t *testing.T) {
+	tc := []struct {
+		Value    string
+		Expected Auth
+		Err      bool
+	}{
+		{Value: "user:secret", Expected: Auth{Username: "user", Password: "secret"}},
+		{Value: "user:pa:ss", Expected: Auth{Username: "user", Password: "pa:ss"}},
+		{Value: "user:", Expected: Auth{Username: "user"}},
+		{Value: "bearer:abc", Expected: Auth{Token: "abc"}},
+		{Value: "Bearer:abc", Expected: Auth{Token: "abc"}},
+		{Value: "user", Err: true},
+		{Value: ":secret", Err: true},
+	}
+
+	for _, v := range tc {
+		auth, err := ParseAuth(v.Value)
+		if v.Err {
+			assert.Error(t, err, v.Value)
+			continue
+		}
+
+		assert.NoError(t, err, v.Value)
+		assert.Equal(t, v.Expected, auth, v.Value)
+	}
+}
+
+func TestCrawler_newRequest(t *testing.T) {
+	u, _ := url.Parse("https://example.com/")
+	crawler := NewWithConfig(*u, Config{
+		UserAgent: "test-robot",
+		Headers:   http.Header{"X-Env": []string{"staging"}},
+		Auth:      Auth{Token: "abc"},
+	})
+
+	request, err := crawler.newRequest(context.Background(), http.MethodGet, "https://example.com/1/", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-robot", request.Header.Get("User-Agent"))
+	assert.Equal(t, "staging", request.Header.Get("X-Env"))
+	assert.Equal(t, "Bearer abc", request.Header.Get("Authorization"))
+
+	request, err = crawler.newRequest(context.Background(), http.MethodGet, "https://cdn.example.com/sitemap.xml", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "staging", request.Header.Get("X-Env"))
+	assert.Empty(t, request.Header.Get("Authorization"))
+}
+
+func TestCrawler_RunWithAuth(t *testing.T) {
+	s := test.NewProtectedServer()
+	s.Start()
+	defer s.Close()
+
+	tc := []struct {
+		Name   string
+		Config Config
+	}{
+		{Name: "basic", Config: Config{Auth: Auth{Username: test.AuthUser, Password: test.AuthPassword}}},
+		{Name: "bearer", Config: Config{Auth: Auth{Token: test.AuthToken}}},
+		{Name: "header", Config: Config{Headers: http.Header{test.AuthHeader: []string{test.AuthHeaderKey}}}},
+		{Name: "login", Config: Config{Login: &LoginForm{
+			URL:    "/login",
+			Fields: url.Values{"username": {test.AuthUser}, "password": {test.AuthPassword}},
+		}}},
+	}
+
+	for _, v := range tc {
+		v.Config.MaxWorkers = 1
+		v.Config.IgnoreRobotsTxt = true
+		crawler := NewWithConfig(*s.Url, v.Config)
+		err := crawler.Run()
+		if err != nil {
+			t.Error(err)
+		}
+
+		assert.Len(t, crawler.SiteMap, 3, v.Name)
+	}
+}
+
+func TestCrawler_RunWithoutAuth(t *testing.T) {
+	s := test.NewProtectedServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		KeepErrorPages:  true,
+		Auth:            Auth{Username: test.AuthUser, Password: "wrong"},
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Len(t, crawler.SiteMap, 1)
+	assert.Equal(t, http.StatusUnauthorized, crawler.SiteMap[s.URL+"/"].Response.StatusCode)
+}
+
+func TestCrawler_RunWithFailedLogin(t *testing.T) {
+	s := test.NewProtectedServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		Login: &LoginForm{
+			URL:    "/login",
+			Fields: url.Values{"username": {test.AuthUser}, "password": {"wrong"}},
+		},
+	})
+	assert.EqualError(t, crawler.Run(), fmt.Sprintf("login to %s/login failed: 401 Unauthorized", s.URL))
+}
+
+func TestCrawler_RunWithCookieFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := test.NewProtectedServer()
+	s.Start()
+	defer s.Close()
+
+	cookies := filepath.Join(dir, "cookies.txt")
+	err = ioutil.WriteFile(cookies, []byte(fmt.Sprintf(
+		"# Netscape HTTP Cookie File\n%s\tFALSE\t/\tFALSE\t0\t%s\t%s\n",
+		s.Url.Hostname(), test.SessionCookie, test.SessionValue,
+	)), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		CookieFile:      cookies,
+	})
+	err = crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Len(t, crawler.SiteMap, 3)
+}
+
+func TestLoadCookies(t *testing.T) {
+	jar, _ := cookiejar.New(nil)
+	expired := time.Now().Add(-time.Hour).Unix()
+	future := time.Now().Add(time.Hour).Unix()
+
+	err := loadCookies(jar, strings.NewReader(fmt.Sprintf(`# Netscape HTTP Cookie File
+
+.example.com	TRUE	/	FALSE	%d	domain	1
+#HttpOnly_example.com	FALSE	/	TRUE	0	secure	2
+example.com	FALSE	/docs/	FALSE	0	docs	3
+example.com	FALSE	/	FALSE	%d	expired	4
+`, future, expired)))
+	assert.NoError(t, err)
+
+	names := func(u string) []string {
+		parsed, _ := url.Parse(u)
+		var names []string
+		for _, c := range jar.Cookies(parsed) {
+			names = append(names, c.Name)
+		}
+		return names
+	}
+
+	assert.ElementsMatch(t, []string{"domain"}, names("http://www.example.com/"))
+	assert.ElementsMatch(t, []string{"domain", "secure"}, names("https://example.com/"))
+	assert.ElementsMatch(t, []string{"domain", "secure", "docs"}, names("https://example.com/docs/"))
+
+	err = loadCookies(jar, strings.NewReader("example.com\tFALSE\t/\n"))
+	assert.EqualError(t, err, "line 1: needs 7 tab separated fields")
+}
@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	"sort"
+)
+
+// BrokenLink is a link from a crawled page to
+// a page that couldn't be crawled, or that was
+// rate limited so it's unknown if it's broken
+type BrokenLink struct {
+	Source        string        `json:"source"`
+	Target        string        `json:"target"`
+	AnchorText    string        `json:"anchor_text"`
+	StatusCode    int           `json:"status_code,omitempty"`
+	ErrorCategory ErrorCategory `json:"error_category"`
+	Error         string        `json:"error"`
+
+	// SoftNotFound is set if the target was served
+	// successfully but looks like a not found page
+	SoftNotFound bool `json:"soft_not_found,omitempty"`
+
+	// Unknown is set if the target was rate limited,
+	// so it couldn't be checked and may not be broken
+	Unknown bool `json:"unknown,omitempty"`
+
+	// LinkedFromCount is the number of pages
+	// that link to the target
+	LinkedFromCount int `json:"linked_from_count"`
+}
+
+// Broken checks if the error category means the page is
+// broken, pages that aren't html or couldn't be parsed
+// were fetched fine so aren't broken, and pages blocked
+// by the robots.txt or rate limited weren't fetched at all
+func (c ErrorCategory) Broken() bool {
+	switch c {
+	case "", ErrorNonHTML, ErrorParse, ErrorRobots, ErrorRateLimited:
+		return false
+	}
+	return true
+}
+
+// BrokenLinks passes back every link to a broken page, or a soft
+// 404, sorted by target then source, the error pages need to be
+// kept in the sitemap for these to be found, see
+// `Config.KeepErrorPages` and `Config.DetectSoftNotFound`. Only
+// the internal links are checked, along with the assets on other
+// hosts with `Config.CheckAssets`, as links to other sites aren't
+// crawled. Links to rate limited pages are passed back as unknown
+func (s SiteMap) BrokenLinks() []BrokenLink {
+	var broken []BrokenLink
+	for _, page := range s {
+		for _, l := range page.Links {
+			target, ok := s[l.URL.String()]
+			if !ok {
+				continue
+			}
+
+			unknown := target.Response.ErrorCategory == ErrorRateLimited
+			if !(target.Response.ErrorCategory.Broken() || target.SoftNotFound || unknown) {
+				continue
+			}
+
+			broken = append(broken, BrokenLink{
+				Source:          page.URL.String(),
+				Target:          target.URL.String(),
+				AnchorText:      l.Text,
+				StatusCode:      target.Response.StatusCode,
+				ErrorCategory:   target.Response.ErrorCategory,
+				Error:           target.Response.Error,
+				SoftNotFound:    target.SoftNotFound,
+				Unknown:         unknown,
+				LinkedFromCount: len(target.LinkedFrom),
+			})
+		}
+	}
+
+	sort.Slice(broken, func(i, j int) bool {
+		if broken[i].Target != broken[j].Target {
+			return broken[i].Target < broken[j].Target
+		}
+		return broken[i].Source < broken[j].Source
+	})
+
+	return broken
+}
@@ -0,0 +1,101 @@
+package crawler
+
+import (
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestSiteMap_BrokenLinks(t *testing.T) {
+	s := test.NewStatusServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		KeepErrorPages:  true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, []BrokenLink{
+		{
+			Source:          s.URL + "/",
+			Target:          s.URL + "/404/",
+			AnchorText:      "Missing page",
+			StatusCode:      http.StatusNotFound,
+			ErrorCategory:   ErrorClient,
+			Error:           "404 Not Found",
+			LinkedFromCount: 2,
+		},
+		{
+			Source:          s.URL + "/about/",
+			Target:          s.URL + "/404/",
+			AnchorText:      "gone",
+			StatusCode:      http.StatusNotFound,
+			ErrorCategory:   ErrorClient,
+			Error:           "404 Not Found",
+			LinkedFromCount: 2,
+		},
+		{
+			Source:          s.URL + "/",
+			Target:          s.URL + "/500/",
+			AnchorText:      "500",
+			StatusCode:      http.StatusInternalServerError,
+			ErrorCategory:   ErrorServer,
+			Error:           "500 Internal Server Error",
+			LinkedFromCount: 1,
+		},
+	}, crawler.SiteMap.BrokenLinks())
+}
+
+func TestSiteMap_BrokenLinks_RateLimited(t *testing.T) {
+	log := &test.RequestLog{}
+	s := test.NewRetryServer(log)
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		KeepErrorPages:  true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	unknown := make(map[string]bool)
+	for _, l := range crawler.SiteMap.BrokenLinks() {
+		unknown[l.Target] = l.Unknown
+		if l.Unknown {
+			assert.Equal(t, ErrorRateLimited, l.ErrorCategory)
+			assert.Equal(t, http.StatusTooManyRequests, l.StatusCode)
+		}
+	}
+
+	assert.Equal(t, map[string]bool{
+		s.URL + "/flaky/":   false,
+		s.URL + "/down/":    false,
+		s.URL + "/missing/": false,
+		s.URL + "/limited/": true,
+	}, unknown)
+}
+
+func TestSiteMap_BrokenLinks_ErrorPagesDropped(t *testing.T) {
+	s := test.NewStatusServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := New(*s.Url, true, 1, "")
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Empty(t, crawler.SiteMap.BrokenLinks())
+}
@@ -0,0 +1,186 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Severity is how serious an issue found by an audit is
+type Severity string
+
+// The severities of the issues, errors stop the tags
+// from working, warnings are likely mistakes
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// CanonicalIssue is the problem with the
+// canonical or hreflang tags of a page
+type CanonicalIssue string
+
+// The problems the canonical and hreflang tags of a page can have
+const (
+	CanonicalMissing        CanonicalIssue = "canonical_missing"
+	CanonicalOtherHost      CanonicalIssue = "canonical_other_host"
+	CanonicalRedirect       CanonicalIssue = "canonical_redirect"
+	CanonicalError          CanonicalIssue = "canonical_error"
+	CanonicalNoIndex        CanonicalIssue = "canonical_noindex"
+	CanonicalChain          CanonicalIssue = "canonical_chain"
+	HreflangNotReciprocal   CanonicalIssue = "hreflang_not_reciprocal"
+	HreflangMissingXDefault CanonicalIssue = "hreflang_missing_x_default"
+)
+
+// hreflangXDefault is the hreflang of the page used
+// when none of the languages match the user
+const hreflangXDefault = "x-default"
+
+// canonicalSeverities are the severities of the issues
+var canonicalSeverities = map[CanonicalIssue]Severity{
+	CanonicalMissing:        SeverityWarning,
+	CanonicalOtherHost:      SeverityWarning,
+	CanonicalRedirect:       SeverityError,
+	CanonicalError:          SeverityError,
+	CanonicalNoIndex:        SeverityError,
+	CanonicalChain:          SeverityWarning,
+	HreflangNotReciprocal:   SeverityError,
+	HreflangMissingXDefault: SeverityWarning,
+}
+
+// CanonicalFinding is an issue with the canonical or hreflang tags
+// of a page, `Target` is the url the tag points at and `Detail`
+// explains the issue, e.g. the status of the target
+type CanonicalFinding struct {
+	URL      string         `json:"url"`
+	Severity Severity       `json:"severity"`
+	Issue    CanonicalIssue `json:"issue"`
+	Target   string         `json:"target,omitempty"`
+	Detail   string         `json:"detail,omitempty"`
+}
+
+// crawledOK checks if the page is an html page that was
+// crawled without errors or redirects and isn't a soft 404
+func (p *Page) crawledOK() bool {
+	return !p.IsRedirect && !p.Asset && p.Response.ErrorCategory == "" && !p.SoftNotFound
+}
+
+// AuditCanonicals checks the canonical and hreflang tags of the
+// crawled pages, sorted by url then issue. Canonicals should point
+// at an indexable page on the same host that is its own canonical,
+// and each page should have one. Hreflang sets should include an
+// `x-default` and each alternate should link back. Tags pointing at
+// pages that weren't crawled are only checked for their host, and
+// the error pages need to be kept in the sitemap for canonicals to
+// them to be found, see `Config.KeepErrorPages`
+func (s SiteMap) AuditCanonicals() []CanonicalFinding {
+	var findings []CanonicalFinding
+	for key, page := range s {
+		if !page.crawledOK() {
+			continue
+		}
+
+		add := func(issue CanonicalIssue, target, detail string) {
+			findings = append(findings, CanonicalFinding{
+				URL:      key,
+				Severity: canonicalSeverities[issue],
+				Issue:    issue,
+				Target:   target,
+				Detail:   detail,
+			})
+		}
+
+		s.auditCanonical(page, add)
+		s.auditHreflang(page, add)
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.URL != b.URL {
+			return a.URL < b.URL
+		}
+		if a.Issue != b.Issue {
+			return a.Issue < b.Issue
+		}
+		return a.Target < b.Target
+	})
+
+	return findings
+}
+
+// auditCanonical checks the canonical of the page
+// and the page it points at
+func (s SiteMap) auditCanonical(page *Page, add func(CanonicalIssue, string, string)) {
+	canonical := page.Info.Canonical
+	if canonical == "" {
+		add(CanonicalMissing, "", "")
+		return
+	}
+
+	if canonical == page.URL.String() {
+		return
+	}
+
+	if u, err := url.Parse(canonical); err == nil && u.Host != page.URL.Host {
+		add(CanonicalOtherHost, canonical, "")
+		return
+	}
+
+	target, ok := s[canonical]
+	switch {
+	case !ok:
+	case target.IsRedirect:
+		detail := ""
+		if target.RedirectsTo != nil {
+			detail = fmt.Sprintf("redirects to %s", target.RedirectsTo)
+		}
+		add(CanonicalRedirect, canonical, detail)
+	case target.Response.ErrorCategory != "":
+		add(CanonicalError, canonical, target.Response.Error)
+	case target.NoIndex:
+		add(CanonicalNoIndex, canonical, "")
+	case target.Info.Canonical != "" && target.Info.Canonical != canonical:
+		add(CanonicalChain, canonical, fmt.Sprintf("canonical of target is %s", target.Info.Canonical))
+	}
+}
+
+// auditHreflang checks the hreflang set of the page has
+// an x-default and that each alternate links back
+func (s SiteMap) auditHreflang(page *Page, add func(CanonicalIssue, string, string)) {
+	if len(page.Info.Hreflang) == 0 {
+		return
+	}
+
+	self := page.URL.String()
+	xDefault := false
+	for _, alternate := range page.Info.Hreflang {
+		if strings.EqualFold(alternate.Lang, hreflangXDefault) {
+			xDefault = true
+		}
+
+		if alternate.URL == self {
+			continue
+		}
+
+		target, ok := s[alternate.URL]
+		if !ok || !target.crawledOK() {
+			continue
+		}
+
+		reciprocal := false
+		for _, back := range target.Info.Hreflang {
+			if back.URL == self {
+				reciprocal = true
+				break
+			}
+		}
+		if !reciprocal {
+			add(HreflangNotReciprocal, alternate.URL, fmt.Sprintf("%s alternate doesn't link back", alternate.Lang))
+		}
+	}
+
+	if !xDefault {
+		add(HreflangMissingXDefault, "", "")
+	}
+}
@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func TestSiteMap_AuditCanonicals(t *testing.T) {
+	s := test.NewCanonicalServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		KeepErrorPages:  true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, []CanonicalFinding{
+		{
+			URL:      s.URL + "/chain/",
+			Severity: SeverityWarning,
+			Issue:    CanonicalChain,
+			Target:   s.URL + "/chain/2/",
+			Detail:   "canonical of target is " + s.URL + "/chain/3/",
+		},
+		{
+			URL:      s.URL + "/de/",
+			Severity: SeverityWarning,
+			Issue:    CanonicalMissing,
+		},
+		{
+			URL:      s.URL + "/de/",
+			Severity: SeverityWarning,
+			Issue:    HreflangMissingXDefault,
+		},
+		{
+			URL:      s.URL + "/de/",
+			Severity: SeverityError,
+			Issue:    HreflangNotReciprocal,
+			Target:   s.URL + "/",
+			Detail:   "en alternate doesn't link back",
+		},
+		{
+			URL:      s.URL + "/error/",
+			Severity: SeverityError,
+			Issue:    CanonicalError,
+			Target:   s.URL + "/gone/",
+			Detail:   "404 Not Found",
+		},
+		{
+			URL:      s.URL + "/noindex/",
+			Severity: SeverityError,
+			Issue:    CanonicalNoIndex,
+			Target:   s.URL + "/hidden/",
+		},
+		{
+			URL:      s.URL + "/other/",
+			Severity: SeverityWarning,
+			Issue:    CanonicalOtherHost,
+			Target:   "https://other.example.com/other/",
+		},
+		{
+			URL:      s.URL + "/redirect/",
+			Severity: SeverityError,
+			Issue:    CanonicalRedirect,
+			Target:   s.URL + "/moved/",
+			Detail:   "redirects to " + s.URL + "/",
+		},
+	}, crawler.SiteMap.AuditCanonicals())
+}
+
+func TestSiteMap_AuditCanonicals_Hreflang(t *testing.T) {
+	s := make(SiteMap)
+	add := func(u string, hreflang ...Hreflang) {
+		parsed, _ := url.Parse(u)
+		s[u] = &Page{URL: *parsed, Info: PageInfo{Canonical: u, Hreflang: hreflang}}
+	}
+
+	en := Hreflang{Lang: "en", URL: "https://example.com/"}
+	fr := Hreflang{Lang: "fr", URL: "https://example.com/fr/"}
+	xDefault := Hreflang{Lang: "X-Default", URL: "https://example.com/"}
+	add(en.URL, en, fr, xDefault)
+	add(fr.URL, en, fr, xDefault)
+	add("https://example.com/about/")
+
+	assert.Empty(t, s.AuditCanonicals())
+
+	s[fr.URL].Info.Hreflang = []Hreflang{fr, {Lang: "x-default", URL: fr.URL}}
+	s[en.URL].Response.ErrorCategory = ErrorServer
+	assert.Empty(t, s.AuditCanonicals())
+
+	s[en.URL].Response.ErrorCategory = ""
+	assert.Equal(t, []CanonicalFinding{
+		{
+			URL:      en.URL,
+			Severity: SeverityError,
+			Issue:    HreflangNotReciprocal,
+			Target:   fr.URL,
+			Detail:   "fr alternate doesn't link back",
+		},
+	}, s.AuditCanonicals())
+}
@@ -0,0 +1,200 @@
+package crawler
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Config is the config for the crawler
+type Config struct {
+	// MaxWorkers is the number of workers in the
+	// crawling pool
+	MaxWorkers int
+
+	// IgnoreRobotsTxt turns off checking the links
+	// against the robots.txt
+	IgnoreRobotsTxt bool
+
+	// UserAgent is the user agent used for the requests
+	// and for checking the robots.txt rules
+	UserAgent string
+
+	// MaxDepth is the maximum click distance from the seed
+	// url that pages are crawled at, 0 means no limit. With
+	// `Sitemaps` the sitemap urls are at depth 0 like the seeds
+	MaxDepth int
+
+	// MaxPages is the maximum number of pages to crawl,
+	// not counting assets, 0 means no limit
+	MaxPages int
+
+	// MaxDuration is the maximum time the crawl can run
+	// for, 0 means no limit
+	MaxDuration time.Duration
+
+	// Include are the scope rules a link needs to match one
+	// of to be crawled, if empty every link is included
+	Include []ScopeRule
+
+	// Exclude are the scope rules that stop a link from being
+	// crawled, excluded links are still kept as out of scope
+	// links on the page
+	Exclude []ScopeRule
+
+	// StayInSeedPath keeps the crawl inside the path of the
+	// seed urls, e.g. a seed of `/product/docs/` only crawls
+	// the links under `/product/docs/`
+	StayInSeedPath bool
+
+	// TrailingSlash is the normalization policy for the
+	// trailing slash on the link paths, defaults to keeping
+	// the paths as they are linked
+	TrailingSlash TrailingSlash
+
+	// LowercasePath lowercases the link paths when
+	// canonicalizing, for sites with case insensitive paths
+	LowercasePath bool
+
+	// SortQuery sorts the query parameters by key when
+	// canonicalizing the links
+	SortQuery bool
+
+	// StripQuery removes the query from the links
+	// when canonicalizing
+	StripQuery bool
+
+	// QueryAllow are the only query parameters kept when
+	// canonicalizing, if empty all are allowed. Can use
+	// patterns, e.g. `page*`
+	QueryAllow []string
+
+	// QueryDeny are the query parameters removed when
+	// canonicalizing, e.g. `utm_*` or `sessionid`
+	QueryDeny []string
+
+	// KeepErrorPages keeps the pages that couldn't be crawled,
+	// e.g. 404s, in the sitemap along with the links to them
+	KeepErrorPages bool
+
+	// RecordHeaders are the response headers recorded for
+	// each page, defaults to `DefaultRecordHeaders`
+	RecordHeaders []string
+
+	// LongRedirectChain is the number of redirects a page can
+	// go through before the chain is warned about, defaults to 3
+	LongRedirectChain int
+
+	// Sitemaps also seeds the crawl from the urls listed in the
+	// sitemaps of the robots.txt, or `/sitemap.xml` if there
+	// are none
+	Sitemaps bool
+
+	// RequestsPerSecond is the maximum rate of requests to
+	// a host, 0 means no limit. The `Crawl-delay` of the
+	// host's robots.txt is used instead if it's stricter
+	RequestsPerSecond float64
+
+	// MaxConnsPerHost is the maximum number of requests to
+	// a host in flight at once, 0 means no limit
+	MaxConnsPerHost int
+
+	// AdaptiveThrottle lowers the number of workers when the
+	// server responds with 429s or 503s or slows down, and
+	// raises it back up to `MaxWorkers` once it's healthy.
+	// The `Retry-After` header of the responses is honored
+	AdaptiveThrottle bool
+
+	// Retry is how pages that failed with a transient
+	// error are retried, by default they aren't
+	Retry RetryPolicy
+
+	// HTTPClient is the client used for the requests, when
+	// set the transport settings below are ignored. It's
+	// copied so the redirects can be recorded, its
+	// CheckRedirect is called after they're recorded
+	HTTPClient *http.Client
+
+	// Transport is the transport used for the requests,
+	// when set the transport settings below are ignored
+	Transport http.RoundTripper
+
+	// RequestTimeout is the total time a request can take,
+	// including reading the body, defaults to 10s
+	RequestTimeout time.Duration
+
+	// ConnectTimeout is the time connecting to the host
+	// can take, defaults to 30s
+	ConnectTimeout time.Duration
+
+	// TLSHandshakeTimeout is the time the tls handshake
+	// can take, defaults to 10s
+	TLSHandshakeTimeout time.Duration
+
+	// ResponseHeaderTimeout is the time to wait for the
+	// response headers once the request is sent, 0 means
+	// only the request timeout applies
+	ResponseHeaderTimeout time.Duration
+
+	// MaxIdleConnsPerHost is the number of connections to
+	// the host kept open for reuse, defaults to `MaxWorkers`
+	MaxIdleConnsPerHost int
+
+	// Proxy is the http proxy the requests go through,
+	// defaults to the proxy environment variables
+	Proxy *url.URL
+
+	// CAFile is a pem bundle of the certificate authorities
+	// to trust along with the system ones
+	CAFile string
+
+	// CertFile and KeyFile are the pem client certificate
+	// and key, for hosts that need mutual tls
+	CertFile string
+	KeyFile  string
+
+	// InsecureSkipVerify turns off checking the tls
+	// certificates of the host
+	InsecureSkipVerify bool
+
+	// Headers are extra headers sent with every request
+	Headers http.Header
+
+	// Auth is the basic auth or bearer token sent with
+	// the requests to the seed host
+	Auth Auth
+
+	// CookieJar is the jar the cookies are kept in, a new
+	// one is used if not set. `CookieFile` is a Netscape
+	// cookies.txt file that's loaded into the jar
+	CookieJar  http.CookieJar
+	CookieFile string
+
+	// Login is a form posted before the crawl starts,
+	// e.g. to get a session cookie
+	Login *LoginForm
+
+	// FollowNoFollow follows the links marked nofollow, by
+	// their rel or the meta robots of the page, which are
+	// otherwise kept but not crawled
+	FollowNoFollow bool
+
+	// LinkRules are the rules for extracting the links from
+	// the pages, defaults to `DefaultLinkRules`
+	LinkRules []LinkRule
+
+	// CheckAssets also requests the links of the rules that
+	// aren't followed, e.g. images and scripts, to check they
+	// work without crawling them as pages, including the ones
+	// on other hosts such as a CDN
+	CheckAssets bool
+
+	// DetectSoftNotFound requests a random url on each host to
+	// learn what its not found page looks like, flagging pages
+	// like it, or with a not found title, as `Page.SoftNotFound`
+	DetectSoftNotFound bool
+
+	// OnProgress is called with the progress of the
+	// crawl each time a page has been crawled
+	OnProgress func(Progress)
+}
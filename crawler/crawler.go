@@ -1,18 +1,16 @@
 package crawler
 
 import (
-	"bytes"
-	"fmt"
+	"context"
+	"errors"
 	"github.com/m1/smap/worker"
-	"github.com/temoto/robotstxt"
-	"io"
 	"net/http"
 	"net/url"
 	"sync"
+	"time"
 )
 
 const (
-	allowAllRobotsTxt     = "User-agent: *\nAllow: /"
 	emailProtectionString = `/cdn-cgi/l/email-protection`
 	contentTypeHTML       = "text/html"
 
@@ -20,30 +18,93 @@ const (
 	headerContentType = "Content-Type"
 
 	tokenAnchor = "a"
+	tokenBase   = "base"
+	tokenMeta   = "meta"
 	attrHref    = "href"
+	attrRel     = "rel"
+	attrName    = "name"
+	attrContent = "content"
+
+	schemeHTTP  = "http"
+	schemeHTTPS = "https"
 )
 
+// ErrCancelled is passed back when the crawl was stopped
+// before finishing because its context was cancelled or
+// its deadline passed, the SiteMap then holds the pages
+// that were crawled up until that point
+var ErrCancelled = errors.New("crawl cancelled")
+
+// ErrTruncated is passed back when one of the crawl limits
+// (max depth, max pages or max duration) was hit, the
+// SiteMap then holds the pages crawled within the limits
+var ErrTruncated = errors.New("crawl truncated, limit reached")
+
 // Crawler is what handles the crawling of the pages including
 // the result and urls to be consumed queues. Also handles
 // and stores the worker pools and the final sitemap
 type Crawler struct {
-	// url is the url that is the base url to start the crawl at
+	// url is the url of the first seed, the host of this is
+	// the host that is crawled
 	url url.URL
 
-	// queued is the urls that are to be crawled, but
-	// haven't been crawled yet
+	// seeds are the urls to start the crawl at
+	seeds []url.URL
+
+	// seedPaths are the paths of the seeds before the trailing
+	// slash policy is applied, that the crawl stays in if
+	// `StayInSeedPath` is set
+	seedPaths []string
+
+	// queued is the canonical urls that are to be crawled,
+	// but haven't been crawled yet
 	queued sync.Map
 
-	ignoreRobotsTxt bool
-	robotsTxtParser *robotstxt.RobotsData
-	userAgent       string
+	config Config
+
+	// rules are the link rules used to extract
+	// the links from the pages
+	rules []LinkRule
+
+	// robots is the robots.txt of each scheme and host,
+	// see `robotsFor`
+	robots   map[string]*robotsEntry
+	robotsMu sync.Mutex
+
+	// notFound is the not found page of each scheme and
+	// host, see `notFoundFor`
+	notFound   map[string]*notFoundEntry
+	notFoundMu sync.Mutex
+
+	// sitemaps are the sitemap urls listed in the robots.txt
+	sitemaps []string
+
+	// inSitemap is the canonical urls listed in the sitemaps
+	inSitemap map[string]bool
+
+	// client is the http client shared by the requests,
+	// see `httpClient`
+	client     *http.Client
+	clientErr  error
+	clientOnce sync.Once
+
+	// limiter paces the requests made to each host
+	limiter *rateLimiter
+
+	// throttle adjusts the number of workers to how the
+	// server is coping, nil if not throttling adaptively
+	throttle *throttle
 
-	// queue is the url chan to be consumed by the worker pool
-	queue chan url.URL
+	// queue is the link chan to be consumed by the worker pool
+	queue chan link
 
 	// results is the channel that handles the crawled pages
 	results chan Page
 
+	// retries is the channel that the pages to be retried
+	// are passed back on once their backoff has passed
+	retries chan *Page
+
 	// SiteMap is the final result of the crawl
 	SiteMap SiteMap
 
@@ -54,105 +115,293 @@ type Crawler struct {
 
 	jobsCreated   int
 	jobsCompleted int
+
+	// pagesCreated are the jobs counted towards
+	// `MaxPages`, i.e. not the assets
+	pagesCreated int
+
+	// next are the links found by the pages being crawled,
+	// added as jobs once the whole level has been crawled so
+	// that pages are found at their shortest click distance
+	next []link
+
+	// truncated is set once any of the crawl limits
+	// stopped pages from being crawled
+	truncated bool
 }
 
 // New passes back a new instance of a crawler
 func New(u url.URL, ignoreRobotsTxt bool, maxWorkers int, userAgent string) *Crawler {
-	u.Path = "/"
+	return NewWithConfig(u, Config{
+		MaxWorkers:      maxWorkers,
+		IgnoreRobotsTxt: ignoreRobotsTxt,
+		UserAgent:       userAgent,
+	})
+}
+
+// NewWithConfig passes back a new instance of a crawler
+// using the config
+func NewWithConfig(u url.URL, config Config) *Crawler {
+	return NewWithSeeds([]url.URL{u}, config)
+}
+
+// NewWithSeeds passes back a new instance of a crawler that
+// starts the crawl at each of the seed urls, the seeds are
+// expected to be on the same host
+func NewWithSeeds(seeds []url.URL, config Config) *Crawler {
+	keepSlash := config
+	keepSlash.TrailingSlash = TrailingSlashKeep
+
+	canonical := make([]url.URL, len(seeds))
+	seedPaths := make([]string, len(seeds))
+	for i, seed := range seeds {
+		canonical[i] = config.canonicalize(seed)
+		seedPaths[i] = keepSlash.canonicalize(seed).Path
+	}
+
+	var u url.URL
+	if len(canonical) > 0 {
+		u = canonical[0]
+	}
+
 	return &Crawler{
-		url:             u,
-		ignoreRobotsTxt: ignoreRobotsTxt,
-		queued:          sync.Map{},
-		pool:            worker.NewPool(maxWorkers),
-		pagesWithErr:    make(map[string]bool),
-		SiteMap:         make(map[string]*Page),
-		userAgent:       userAgent,
+		url:          u,
+		seeds:        canonical,
+		seedPaths:    seedPaths,
+		config:       config,
+		rules:        config.linkRules(),
+		queued:       sync.Map{},
+		pool:         worker.NewPool(config.MaxWorkers),
+		pagesWithErr: make(map[string]bool),
+		inSitemap:    make(map[string]bool),
+		SiteMap:      make(map[string]*Page),
 	}
 }
 
 // Run starts the crawling, and setting up/closing of the channels
 func (c *Crawler) Run() error {
-	if !c.ignoreRobotsTxt {
-		err := c.robotsInit()
-		if err != nil {
+	return c.RunContext(context.Background())
+}
+
+// RunContext starts the crawling the same as `Run`, but stops
+// early once the context is done. When stopped early the
+// SiteMap holds the pages crawled so far and `ErrCancelled`
+// is passed back, when stopped by one of the crawl limits
+// `ErrTruncated` is passed back instead
+func (c *Crawler) RunContext(ctx context.Context) error {
+	parent := ctx
+	if c.config.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.MaxDuration)
+		defer cancel()
+	}
+
+	err := c.run(ctx)
+	if err == ErrCancelled && parent.Err() == nil {
+		c.truncated = true
+		err = nil
+	}
+
+	if err == nil && c.truncated {
+		return ErrTruncated
+	}
+
+	return err
+}
+
+func (c *Crawler) run(ctx context.Context) error {
+	if _, err := c.httpClient(); err != nil {
+		return err
+	}
+
+	if c.config.Login != nil {
+		if err := c.login(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ErrCancelled
+			}
 			return err
 		}
 	}
-	c.queue = make(chan url.URL)
+
+	if !c.config.IgnoreRobotsTxt {
+		c.robotsInit(ctx)
+		if ctx.Err() != nil {
+			return ErrCancelled
+		}
+	}
+
+	c.limiter = c.newRateLimiter()
+	if c.config.AdaptiveThrottle {
+		c.throttle = newThrottle(c.config.MaxWorkers)
+	}
+
+	var sitemapURLs []url.URL
+	if c.config.Sitemaps {
+		var err error
+		sitemapURLs, err = c.sitemapInit(ctx)
+		if err != nil {
+			return ErrCancelled
+		}
+	}
+
+	c.queue = make(chan link)
 	defer close(c.queue)
 
 	c.results = make(chan Page)
 	defer close(c.results)
 
-	c.pool.Start()
-	c.addJob(c.url)
+	c.retries = make(chan *Page)
 
-	c.waitForResults()
-	c.cleanUpResults()
-
-	c.generateLinksFrom()
+	c.pool.StartContext(ctx)
+	for _, seed := range c.seeds {
+		if _, loaded := c.queued.LoadOrStore(seed.String(), true); loaded {
+			continue
+		}
 
-	return nil
-}
+		if allowed, rule := c.robotsAllowed(ctx, &seed); !allowed {
+			c.keepBlocked(seed, rule)
+			continue
+		}
 
-// robotsInit tries to fetch the robots.txt of the base url
-func (c *Crawler) robotsInit() error {
-	resp, err := http.Get(fmt.Sprintf("%s://%s/robots.txt", c.url.Scheme, c.url.Host))
-	if err != nil {
-		return err
+		c.addJob(link{URL: seed})
 	}
+	c.addSitemapURLs(ctx, sitemapURLs)
 
-	body := resp.Body.(io.Reader)
-	if resp.StatusCode > 400 {
-		body = bytes.NewReader([]byte(allowAllRobotsTxt))
-	}
+	err := c.waitForResults(ctx)
+	c.pool.Close()
+	c.cleanUpResults()
 
-	buf := new(bytes.Buffer)
-	_, err = buf.ReadFrom(body)
-	if err != nil {
-		return err
-	}
+	c.generateLinksFrom()
+	c.setDiscovery()
 
-	c.robotsTxtParser, err = robotstxt.FromBytes(buf.Bytes())
 	return err
 }
 
-func (c *Crawler) addJob(url url.URL) {
-	page := NewPage(url, c)
+// robotsInit fetches the robots.txt of the seed url,
+// collecting the sitemaps listed in it. A robots.txt that
+// can't be fetched doesn't stop the crawl, see `fetchRobots`
+func (c *Crawler) robotsInit(ctx context.Context) {
+	c.sitemaps = c.robotsFor(ctx, &c.url).sitemaps
+}
+
+// addJob adds the link to the pool to be crawled, unless
+// the max pages limit has been hit. Assets don't count
+// towards the limit
+func (c *Crawler) addJob(l link) {
+	if !l.Asset {
+		if c.config.MaxPages > 0 && c.pagesCreated >= c.config.MaxPages {
+			c.truncated = true
+			return
+		}
+		c.pagesCreated++
+	}
+
+	page := NewPage(l.URL, c)
+	page.Depth = l.Depth
+	page.Asset = l.Asset
 
 	c.pool.AddJob(page)
 	c.jobsCreated++
 }
 
-func (c *Crawler) waitForResults() {
+// waitForResults consumes the queue and results channels
+// until every job created has completed or the context
+// is done, returning straight away if there are no jobs,
+// e.g. every seed was blocked by the robots.txt. The pages are crawled a level of depth at a
+// time, the links found are held in `next` until every
+// page of the level has been crawled
+func (c *Crawler) waitForResults(ctx context.Context) error {
+	if c.jobsCreated == 0 {
+		return nil
+	}
+
 	for {
 		select {
-		case page := <-c.queue:
-			c.addJob(page)
+		case <-ctx.Done():
+			return ErrCancelled
+		case l := <-c.queue:
+			c.next = append(c.next, l)
+		case page := <-c.retries:
+			c.pool.AddJob(page)
 		case result := <-c.results:
+			if result.truncated {
+				c.truncated = true
+			}
+
+			c.observe(&result)
+			if c.config.Retry.retryable(&result) {
+				c.retry(ctx, &result)
+				continue
+			}
+
 			if result.err != nil {
-				c.pagesWithErr[result.URL.Path] = true
-			} else {
+				c.pagesWithErr[result.URL.String()] = true
+			}
+
+			if result.err == nil || c.config.KeepErrorPages {
 				res := &result
-				c.SiteMap[result.URL.Path] = res
+				c.SiteMap[result.URL.String()] = res
 			}
 
 			c.jobsCompleted++
-			if c.jobsCreated == c.jobsCompleted {
-				c.pool.Close()
-				return
+			if c.config.OnProgress != nil {
+				c.config.OnProgress(Progress{
+					Crawled:        c.jobsCompleted,
+					Queued:         c.jobsCreated - c.jobsCompleted + len(c.next),
+					Concurrency:    c.pool.Size(),
+					MaxConcurrency: c.config.MaxWorkers,
+				})
+			}
+
+			if c.jobsCreated == c.jobsCompleted && !c.nextLevel() {
+				return nil
 			}
 		}
 	}
 }
 
+// nextLevel adds the links found by the level just
+// crawled as jobs, passing back false if there are none
+// to crawl
+func (c *Crawler) nextLevel() bool {
+	next := c.next
+	c.next = nil
+	for _, l := range next {
+		c.addJob(l)
+	}
+
+	return c.jobsCreated > c.jobsCompleted
+}
+
+// observe pauses the host if the page asked to be retried
+// later, whether or not the crawl is being throttled, then
+// throttles the crawl according to the response of the page
+func (c *Crawler) observe(p *Page) {
+	if p.retryAfter > 0 {
+		c.limiter.pause(&p.URL, time.Now().Add(p.retryAfter))
+	}
+
+	if c.throttle == nil {
+		return
+	}
+
+	if level, changed := c.throttle.observe(p); changed {
+		c.pool.Resize(level)
+	}
+}
+
 // cleanUpResults removes links from the SiteMap that
-// produced errors once crawled
+// produced errors once crawled, unless the error
+// pages are being kept
 func (c *Crawler) cleanUpResults() {
+	if c.config.KeepErrorPages {
+		return
+	}
+
 	for path, page := range c.SiteMap {
 		var errChecked links
 		for _, link := range page.Links {
-			exists, ok := c.pagesWithErr[link.URL.Path]
+			exists, ok := c.pagesWithErr[link.URL.String()]
 			if !ok || !exists {
 				errChecked = append(errChecked, link)
 			}
@@ -163,13 +412,27 @@ func (c *Crawler) cleanUpResults() {
 
 // generateLinksFrom traverses through the crawled
 // pages and finds where pages were linked from
-// and stores them
+// and stores them, links to pages that weren't crawled
+// because the crawl was cancelled are skipped. Also
+// stores the raw variants of the links on the pages
+// they were canonicalized to
 func (c *Crawler) generateLinksFrom() {
 	for _, page := range c.SiteMap {
 		for _, l := range page.Links {
-			c.SiteMap[l.URL.Path].appendLinkedFrom(link{
+			linked, ok := c.SiteMap[l.URL.String()]
+			if !ok {
+				continue
+			}
+
+			linked.appendLinkedFrom(link{
 				URL: page.URL,
 			})
 		}
+
+		for key, raws := range page.linkVariants {
+			if linked, ok := c.SiteMap[key]; ok {
+				linked.appendVariants(raws)
+			}
+		}
 	}
 }
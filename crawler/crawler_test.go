@@ -1,9 +1,13 @@
 package crawler
 
 import (
+	"context"
 	"github.com/m1/smap/test"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
 	"testing"
+	"time"
 )
 
 type testCase struct {
@@ -12,6 +16,7 @@ type testCase struct {
 	LinkedFrom []string
 	Redirect   bool
 	RedirectTo string
+	Depth      int
 }
 
 func TestCrawler_Run(t *testing.T) {
@@ -20,16 +25,19 @@ func TestCrawler_Run(t *testing.T) {
 		Path:       "/",
 		Links:      []string{"/1/"},
 		LinkedFrom: []string{"/2/"},
+		Depth:      0,
 	}
 	tc["/1/"] = testCase{
 		Path:       "/1/",
 		Links:      []string{"/2/"},
 		LinkedFrom: []string{"/", "/2/"},
+		Depth:      1,
 	}
 	tc["/2/"] = testCase{
 		Path:       "/2/",
 		Links:      []string{"/", "/1/"},
 		LinkedFrom: []string{"/1/"},
+		Depth:      2,
 	}
 
 	s := test.NewServer()
@@ -43,16 +51,243 @@ func TestCrawler_Run(t *testing.T) {
 	}
 
 	for k, v := range tc {
-		sm, ok := crawler.SiteMap[k]
+		sm, ok := crawler.SiteMap[s.URL+k]
 		if !ok {
 			t.Error("should exist")
 		}
 
 		assert.ElementsMatch(t, v.Links, sm.Links.Paths())
 		assert.ElementsMatch(t, v.LinkedFrom, sm.LinkedFrom.Paths())
+		assert.Equal(t, v.Depth, sm.Depth)
 	}
 }
 
+func TestCrawler_Run_MaxDepth(t *testing.T) {
+	s := test.NewServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		MaxDepth:        1,
+	})
+	err := crawler.Run()
+	assert.Equal(t, ErrTruncated, err)
+
+	assert.ElementsMatch(t, []string{"/", "/1/"}, crawler.SiteMap.PathsCrawled())
+	assert.ElementsMatch(t, []string{"/2/"}, crawler.SiteMap[s.URL+"/1/"].Links.Paths())
+	assert.ElementsMatch(t, []string{"/"}, crawler.SiteMap[s.URL+"/1/"].LinkedFrom.Paths())
+	assert.Equal(t, 1, crawler.SiteMap[s.URL+"/1/"].Depth)
+}
+
+func TestCrawler_Run_MaxDepth_NotHit(t *testing.T) {
+	s := test.NewServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		MaxDepth:        2,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.ElementsMatch(t, []string{"/", "/1/", "/2/"}, crawler.SiteMap.PathsCrawled())
+}
+
+func TestCrawler_Run_MaxDepth_ShortestPath(t *testing.T) {
+	s := test.NewDepthServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      2,
+		IgnoreRobotsTxt: true,
+		MaxDepth:        3,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	depths := make(map[string]int)
+	for _, page := range crawler.SiteMap {
+		depths[page.URL.Path] = page.Depth
+	}
+	assert.Equal(t, map[string]int{
+		"/":        0,
+		"/slow/":   1,
+		"/fast/":   1,
+		"/fast/2/": 2,
+		"/x/":      2,
+		"/y/":      3,
+	}, depths)
+}
+
+func TestCrawler_Run_SharedLink(t *testing.T) {
+	log := &test.RequestLog{}
+	s := test.NewSharedLinkServer(log)
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      6,
+		IgnoreRobotsTxt: true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	shared := 0
+	for _, path := range log.Paths() {
+		if path == "/shared/" {
+			shared++
+		}
+	}
+	assert.Equal(t, 1, shared)
+	assert.Len(t, crawler.SiteMap, 8)
+	assert.Equal(t, 8, crawler.pagesCreated)
+}
+
+func TestCrawler_Run_Exclude(t *testing.T) {
+	s := test.NewServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		Exclude:         []ScopeRule{PathPrefix("/2/")},
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.ElementsMatch(t, []string{"/", "/1/"}, crawler.SiteMap.PathsCrawled())
+	assert.ElementsMatch(t, []string{"/2/"}, crawler.SiteMap[s.URL+"/1/"].Links.Paths())
+	assert.ElementsMatch(t, []string{"/2/"}, crawler.SiteMap[s.URL+"/1/"].Links.OutOfScopePaths())
+	assert.Empty(t, crawler.SiteMap[s.URL+"/"].Links.OutOfScopePaths())
+}
+
+func TestCrawler_Run_StayInSeedPath(t *testing.T) {
+	s := test.NewDocsServer()
+	s.Start()
+	defer s.Close()
+
+	seed := *s.Url
+	seed.Path = "/docs/"
+	crawler := NewWithSeeds([]url.URL{seed}, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		StayInSeedPath:  true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.ElementsMatch(t, []string{"/docs/", "/docs/1/"}, crawler.SiteMap.PathsCrawled())
+	assert.ElementsMatch(t, []string{"/"}, crawler.SiteMap[s.URL+"/docs/"].Links.OutOfScopePaths())
+	assert.ElementsMatch(t, []string{"/blog/"}, crawler.SiteMap[s.URL+"/docs/1/"].Links.OutOfScopePaths())
+	assert.Equal(t, 0, crawler.SiteMap[s.URL+"/docs/"].Depth)
+}
+
+func TestCrawler_Run_MultipleSeeds(t *testing.T) {
+	s := test.NewDocsServer()
+	s.Start()
+	defer s.Close()
+
+	docs := *s.Url
+	docs.Path = "/docs/1/"
+	blog := *s.Url
+	blog.Path = "/blog/"
+	crawler := NewWithSeeds([]url.URL{docs, blog}, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		StayInSeedPath:  true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.ElementsMatch(t, []string{"/docs/1/", "/blog/"}, crawler.SiteMap.PathsCrawled())
+	assert.ElementsMatch(t, []string{"/docs/1/"}, crawler.SiteMap[s.URL+"/blog/"].LinkedFrom.Paths())
+	assert.Equal(t, 0, crawler.SiteMap[s.URL+"/blog/"].Depth)
+}
+
+func TestCrawler_Run_Canonical(t *testing.T) {
+	s := test.NewQueryServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		TrailingSlash:   TrailingSlashAdd,
+		LowercasePath:   true,
+		QueryDeny:       []string{"utm_*"},
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.ElementsMatch(t, []string{
+		s.URL + "/",
+		s.URL + "/list/?page=2",
+		s.URL + "/list/?page=3",
+		s.URL + "/about/",
+	}, crawler.SiteMap.URLsCrawled())
+
+	assert.ElementsMatch(t, []string{
+		s.URL + "/list?page=2",
+		s.URL + "/list?utm_source=home&page=2",
+	}, crawler.SiteMap[s.URL+"/list/?page=2"].Variants)
+	assert.ElementsMatch(t, []string{
+		s.URL + "/About",
+	}, crawler.SiteMap[s.URL+"/about/"].Variants)
+	assert.Len(t, crawler.SiteMap[s.URL+"/"].Links, 3)
+}
+
+func TestCrawler_Run_MaxPages(t *testing.T) {
+	s := test.NewServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		MaxPages:        2,
+	})
+	err := crawler.Run()
+	assert.Equal(t, ErrTruncated, err)
+
+	assert.ElementsMatch(t, []string{"/", "/1/"}, crawler.SiteMap.PathsCrawled())
+}
+
+func TestCrawler_Run_MaxDuration(t *testing.T) {
+	s := test.NewSlowServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      2,
+		IgnoreRobotsTxt: true,
+		MaxDuration:     200 * time.Millisecond,
+	})
+	err := crawler.Run()
+	assert.Equal(t, ErrTruncated, err)
+
+	assert.ElementsMatch(t, []string{"/"}, crawler.SiteMap.PathsCrawled())
+}
+
 func TestCrawler_Run_WithRedirect(t *testing.T) {
 	tc := make(map[string]testCase)
 	tc["/"] = testCase{
@@ -80,7 +315,7 @@ func TestCrawler_Run_WithRedirect(t *testing.T) {
 	}
 
 	for k, v := range tc {
-		sm, ok := crawler.SiteMap[k]
+		sm, ok := crawler.SiteMap[s.URL+k]
 		if !ok {
 			t.Error("should exist")
 		}
@@ -119,7 +354,7 @@ func TestCrawler_Run_WithLinksErr(t *testing.T) {
 	}
 
 	for k, v := range tc {
-		sm, ok := crawler.SiteMap[k]
+		sm, ok := crawler.SiteMap[s.URL+k]
 		if !ok {
 			t.Error("should exist")
 		}
@@ -129,6 +364,30 @@ func TestCrawler_Run_WithLinksErr(t *testing.T) {
 	}
 }
 
+func TestCrawler_RunContext_Cancelled(t *testing.T) {
+	s := test.NewSlowServer()
+	s.Start()
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	crawler := New(*s.Url, true, 2, "")
+	err := crawler.RunContext(ctx)
+	assert.Equal(t, ErrCancelled, err)
+
+	sm, ok := crawler.SiteMap[s.URL+"/"]
+	if !ok {
+		t.Fatal("should exist")
+	}
+
+	assert.ElementsMatch(t, []string{"/1/"}, sm.Links.Paths())
+	assert.Empty(t, sm.LinkedFrom.Paths())
+
+	_, ok = crawler.SiteMap[s.URL+"/1/"]
+	assert.False(t, ok)
+}
+
 func TestCrawler_RunWithRobotsTxt_Disallow(t *testing.T) {
 	tc := make(map[string]testCase)
 	tc["/"] = testCase{
@@ -154,7 +413,7 @@ func TestCrawler_RunWithRobotsTxt_Disallow(t *testing.T) {
 	}
 
 	for k, v := range tc {
-		sm, ok := crawler.SiteMap[k]
+		sm, ok := crawler.SiteMap[s.URL+k]
 		if !ok {
 			t.Error("should exist")
 		}
@@ -162,6 +421,10 @@ func TestCrawler_RunWithRobotsTxt_Disallow(t *testing.T) {
 		assert.ElementsMatch(t, v.Links, sm.Links.Paths())
 		assert.ElementsMatch(t, v.LinkedFrom, sm.LinkedFrom.Paths())
 	}
+
+	assert.Equal(t, []BlockedURL{
+		{URL: s.URL + "/2/", Rule: "Disallow: /2/"},
+	}, crawler.SiteMap.Blocked())
 }
 
 func TestCrawler_RunWithRobotsTxt_BufError(t *testing.T) {
@@ -170,13 +433,22 @@ func TestCrawler_RunWithRobotsTxt_BufError(t *testing.T) {
 	s.Start()
 	defer s.Close()
 
-	crawler := New(*s.Url, false, 1, "test-robot")
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:     1,
+		UserAgent:      "test-robot",
+		KeepErrorPages: true,
+	})
 	err := crawler.Run()
-	if err == nil {
-		t.Error("expecting error")
+	if err != nil {
+		t.Error(err)
 	}
 
-	assert.EqualError(t, err, "unexpected EOF")
+	if assert.Len(t, crawler.SiteMap, 1) {
+		assert.Equal(t, ErrorRobots, crawler.SiteMap[s.URL+"/"].Response.ErrorCategory)
+	}
+	assert.Equal(t, []BlockedURL{
+		{URL: s.URL + "/", Rule: "robots.txt unreachable (unexpected EOF)"},
+	}, crawler.SiteMap.Blocked())
 }
 
 func TestCrawler_RunWithRobotsTxt_500Error(t *testing.T) {
@@ -191,7 +463,49 @@ func TestCrawler_RunWithRobotsTxt_500Error(t *testing.T) {
 		t.Error(err)
 	}
 
-	assert.Empty(t, crawler.robotsTxtParser.Sitemaps)
+	assert.Empty(t, crawler.sitemaps)
+	assert.Empty(t, crawler.SiteMap)
+
+	crawler = NewWithConfig(*s.Url, Config{
+		MaxWorkers:     1,
+		UserAgent:      "test-robot",
+		KeepErrorPages: true,
+	})
+	err = crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if assert.Len(t, crawler.SiteMap, 1) {
+		assert.Equal(t, ErrorRobots, crawler.SiteMap[s.URL+"/"].Response.ErrorCategory)
+	}
+	assert.Equal(t, []BlockedURL{
+		{URL: s.URL + "/", Rule: "robots.txt unreachable (503 Service Unavailable)"},
+	}, crawler.SiteMap.Blocked())
+}
+
+func TestCrawler_RunWithRobotsTxt_SeedDisallowed(t *testing.T) {
+	s := test.NewServer()
+	s.WithRobotsStatus(http.StatusOK)
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:     1,
+		UserAgent:      "test-robot",
+		KeepErrorPages: true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if assert.Len(t, crawler.SiteMap, 1) {
+		assert.Equal(t, ErrorRobots, crawler.SiteMap[s.URL+"/"].Response.ErrorCategory)
+	}
+	assert.Equal(t, []BlockedURL{
+		{URL: s.URL + "/", Rule: "Disallow: /"},
+	}, crawler.SiteMap.Blocked())
 }
 
 func TestCrawler_RunWithRobotsTxt_GetError(t *testing.T) {
@@ -203,9 +517,11 @@ func TestCrawler_RunWithRobotsTxt_GetError(t *testing.T) {
 
 	crawler := New(*s.Url, false, 1, "test-robot")
 	err := crawler.Run()
-	if err == nil {
+	if err != nil {
 		t.Error(err)
 	}
 
-	assert.Contains(t, err.Error(), "unsupported protocol scheme")
+	allowed, rule := crawler.robotsAllowed(context.Background(), &crawler.url)
+	assert.False(t, allowed)
+	assert.Contains(t, rule, "unsupported protocol scheme")
 }
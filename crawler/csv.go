@@ -0,0 +1,91 @@
+package crawler
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// csvHeadingSeparator joins the headings of a level
+// into a single csv field
+const csvHeadingSeparator = " | "
+
+// csvHeader are the columns of the csv export
+var csvHeader = []string{
+	"url",
+	"status_code",
+	"error_category",
+	"depth",
+	"redirects_to",
+	"discovered_by",
+	"noindex",
+	"nofollow",
+	"title",
+	"meta_description",
+	"canonical",
+	"lang",
+	"h1",
+	"h2",
+	"h3",
+	"word_count",
+	"images_missing_alt",
+	"content_hash",
+	"links",
+	"linked_from",
+}
+
+// WriteCSV writes the pages sorted by url as csv, a row for
+// each page with its response and on-page metadata. The
+// headings of each level are joined with ` | `
+func (s SiteMap) WriteCSV(w io.Writer) error {
+	var keys []string
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		page := s[key]
+
+		redirectsTo := ""
+		if page.IsRedirect && page.RedirectsTo != nil {
+			redirectsTo = page.RedirectsTo.String()
+		}
+
+		err := writer.Write([]string{
+			page.URL.String(),
+			strconv.Itoa(page.Response.StatusCode),
+			string(page.Response.ErrorCategory),
+			strconv.Itoa(page.Depth),
+			redirectsTo,
+			string(page.DiscoveredBy),
+			strconv.FormatBool(page.NoIndex),
+			strconv.FormatBool(page.NoFollow),
+			page.Info.Title,
+			page.Info.MetaDescription,
+			page.Info.Canonical,
+			page.Info.Lang,
+			strings.Join(page.Info.H1, csvHeadingSeparator),
+			strings.Join(page.Info.H2, csvHeadingSeparator),
+			strings.Join(page.Info.H3, csvHeadingSeparator),
+			strconv.Itoa(page.Info.WordCount),
+			strconv.Itoa(page.Info.ImagesMissingAlt),
+			page.Info.ContentHash,
+			strconv.Itoa(len(page.Links)),
+			strconv.Itoa(len(page.LinkedFrom)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
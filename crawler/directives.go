@@ -0,0 +1,94 @@
+package crawler
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	headerRobotsTag = "X-Robots-Tag"
+
+	metaRobots  = "robots"
+	relNoFollow = "nofollow"
+
+	directiveNoIndex  = "noindex"
+	directiveNoFollow = "nofollow"
+	directiveNone     = "none"
+)
+
+// robotsTagDirectives are the X-Robots-Tag directives that have
+// a value after a colon, so aren't mistaken for a user agent
+var robotsTagDirectives = map[string]bool{
+	"unavailable_after": true,
+	"max-snippet":       true,
+	"max-image-preview": true,
+	"max-video-preview": true,
+}
+
+// applyDirectives sets the index and follow flags of the
+// page from the comma separated directives
+func (p *Page) applyDirectives(directives string) {
+	for _, directive := range strings.Split(directives, ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case directiveNoIndex:
+			p.NoIndex = true
+		case directiveNoFollow:
+			p.NoFollow = true
+		case directiveNone:
+			p.NoIndex = true
+			p.NoFollow = true
+		}
+	}
+}
+
+// applyRobotsTag applies the X-Robots-Tag headers of the
+// response, headers aimed at other user agents with the
+// `agent: directives` format are skipped, the agent having
+// to be the product token of the user agent
+func (p *Page) applyRobotsTag(header http.Header) {
+	for _, value := range header.Values(headerRobotsTag) {
+		if i := strings.Index(value, ":"); i >= 0 {
+			agent := strings.ToLower(strings.TrimSpace(value[:i]))
+			if !strings.ContainsAny(agent, ", ") && !robotsTagDirectives[agent] {
+				if !agentMatches(p.crawler.config.UserAgent, agent) {
+					continue
+				}
+				value = value[i+1:]
+			}
+		}
+
+		p.applyDirectives(value)
+	}
+}
+
+// applyMetaRobots applies the directives of a `<meta>` tag
+// named `robots` or the product token of the user agent
+func (p *Page) applyMetaRobots(name, content string) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == metaRobots || agentMatches(p.crawler.config.UserAgent, name) {
+		p.applyDirectives(content)
+	}
+}
+
+// hasRel checks if the space separated rel
+// attribute has the link type
+func hasRel(rel, linkType string) bool {
+	for _, t := range strings.Fields(rel) {
+		if strings.EqualFold(t, linkType) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithoutNoIndex passes back the pages of the
+// sitemap that aren't marked noindex
+func (s SiteMap) WithoutNoIndex() SiteMap {
+	pages := make(SiteMap)
+	for key, page := range s {
+		if !page.NoIndex {
+			pages[key] = page
+		}
+	}
+	return pages
+}
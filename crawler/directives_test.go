@@ -0,0 +1,141 @@
+package crawler
+
+import (
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestCrawler_RunWithDirectives(t *testing.T) {
+	s := test.NewDirectivesServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := New(*s.Url, true, 1, "Test-Robot/1.0")
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	tcs := map[string]struct {
+		noIndex  bool
+		noFollow bool
+	}{
+		"/":            {false, false},
+		"/follow/":     {false, false},
+		"/meta/":       {true, true},
+		"/header/":     {true, false},
+		"/agent/":      {false, true},
+		"/agent-meta/": {true, false},
+	}
+
+	assert.Len(t, crawler.SiteMap, len(tcs))
+	for path, tc := range tcs {
+		page, ok := crawler.SiteMap[s.URL+path]
+		if !ok {
+			t.Errorf("%s should exist", path)
+			continue
+		}
+
+		assert.Equal(t, tc.noIndex, page.NoIndex, path)
+		assert.Equal(t, tc.noFollow, page.NoFollow, path)
+	}
+
+	index := crawler.SiteMap[s.URL+"/"]
+	for _, l := range index.Links {
+		assert.Equal(t, l.URL.Path == "/nofollow/", l.NoFollow, l.URL.Path)
+	}
+	assert.Equal(t, []string{"/meta/child/"}, crawler.SiteMap[s.URL+"/meta/"].Links.Paths())
+
+	var indexed []string
+	for key := range crawler.SiteMap.WithoutNoIndex() {
+		indexed = append(indexed, key)
+	}
+	assert.ElementsMatch(t, []string{
+		s.URL + "/",
+		s.URL + "/follow/",
+		s.URL + "/agent/",
+	}, indexed)
+}
+
+func TestCrawler_RunWithFollowNoFollow(t *testing.T) {
+	s := test.NewDirectivesServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		UserAgent:       "test-robot",
+		FollowNoFollow:  true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Contains(t, crawler.SiteMap, s.URL+"/nofollow/")
+	assert.Contains(t, crawler.SiteMap, s.URL+"/meta/child/")
+	assert.True(t, crawler.SiteMap[s.URL+"/meta/"].NoFollow)
+}
+
+func TestPage_applyRobotsTag(t *testing.T) {
+	tcs := []struct {
+		values   []string
+		noIndex  bool
+		noFollow bool
+	}{
+		{[]string{"none"}, true, true},
+		{[]string{"NoIndex, NoFollow"}, true, true},
+		{[]string{"all"}, false, false},
+		{[]string{"googlebot: noindex"}, false, false},
+		{[]string{"smap: noindex", "nofollow"}, true, true},
+		{[]string{"SMAP: noindex"}, true, false},
+		{[]string{"ot: nofollow"}, false, false},
+		{[]string{"sm: noindex"}, false, false},
+		{[]string{"unavailable_after: 25 Jun 2030 15:00:00 PST"}, false, false},
+	}
+
+	u, err := url.Parse("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range tcs {
+		p := NewPage(*u, &Crawler{config: Config{UserAgent: "smap/0.0.1"}})
+		p.applyRobotsTag(http.Header{headerRobotsTag: tc.values})
+
+		assert.Equal(t, tc.noIndex, p.NoIndex, tc.values)
+		assert.Equal(t, tc.noFollow, p.NoFollow, tc.values)
+	}
+}
+
+func TestPage_applyMetaRobots(t *testing.T) {
+	tcs := []struct {
+		name    string
+		noIndex bool
+	}{
+		{"robots", true},
+		{"Robots", true},
+		{"smap", true},
+		{"SMAP", true},
+		{"bot", false},
+		{"sma", false},
+		{"smap/0.0.1", false},
+		{"googlebot", false},
+	}
+
+	u, err := url.Parse("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range tcs {
+		p := NewPage(*u, &Crawler{config: Config{UserAgent: "smap/0.0.1"}})
+		p.applyMetaRobots(tc.name, "noindex")
+
+		assert.Equal(t, tc.noIndex, p.NoIndex, tc.name)
+	}
+}
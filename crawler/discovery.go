@@ -0,0 +1,207 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Discovery is how a page was found by the crawler
+type Discovery string
+
+const (
+	// DiscoverySeed is a page that was only crawled
+	// because it was one of the seed urls
+	DiscoverySeed Discovery = "seed"
+
+	// DiscoveryLinks is a page found by following links
+	DiscoveryLinks Discovery = "links"
+
+	// DiscoverySitemap is a page listed in the site's
+	// sitemaps that no crawled page links to
+	DiscoverySitemap Discovery = "sitemap"
+
+	// DiscoveryBoth is a page listed in the site's
+	// sitemaps that is also linked to
+	DiscoveryBoth Discovery = "both"
+)
+
+const (
+	sitemapPath = "/sitemap.xml"
+
+	// maxSitemapNesting is how deep sitemap indexes are
+	// followed, the protocol doesn't allow nested indexes
+	// but some sites have them anyway
+	maxSitemapNesting = 5
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+var errBlockedByRobots = errors.New("blocked by robots.txt")
+
+// xmlDocument is either a sitemap urlset
+// or a sitemap index
+type xmlDocument struct {
+	URLs     []xmlURL     `xml:"url"`
+	Sitemaps []xmlSitemap `xml:"sitemap"`
+}
+
+// sitemapInit fetches the sitemaps found in the robots.txt,
+// or `/sitemap.xml` if there were none, and passes back the
+// urls listed in them. Sitemaps that can't be fetched or
+// parsed are skipped
+func (c *Crawler) sitemapInit(ctx context.Context) ([]url.URL, error) {
+	locs := c.sitemaps
+	if len(locs) == 0 {
+		locs = []string{fmt.Sprintf("%s://%s%s", c.url.Scheme, c.url.Host, sitemapPath)}
+	}
+
+	var urls []url.URL
+	visited := make(map[string]bool)
+	for _, loc := range locs {
+		urls = append(urls, c.fetchSitemap(ctx, loc, 0, visited)...)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	return urls, nil
+}
+
+// fetchSitemap passes back the urls of the sitemap,
+// following it recursively if it's a sitemap index
+func (c *Crawler) fetchSitemap(ctx context.Context, loc string, nesting int, visited map[string]bool) []url.URL {
+	if nesting > maxSitemapNesting || visited[loc] {
+		return nil
+	}
+	visited[loc] = true
+
+	doc, err := c.getSitemap(ctx, loc)
+	if err != nil {
+		return nil
+	}
+
+	var urls []url.URL
+	for _, u := range doc.URLs {
+		parsed, err := url.Parse(strings.TrimSpace(u.Loc))
+		if err != nil {
+			continue
+		}
+		urls = append(urls, *parsed)
+	}
+
+	for _, s := range doc.Sitemaps {
+		urls = append(urls, c.fetchSitemap(ctx, strings.TrimSpace(s.Loc), nesting+1, visited)...)
+	}
+
+	return urls
+}
+
+// getSitemap fetches and parses the sitemap,
+// gunzipping it if it's gzipped
+func (c *Crawler) getSitemap(ctx context.Context, loc string) (*xmlDocument, error) {
+	request, err := c.newRequest(ctx, http.MethodGet, loc, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := c.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sitemap %s: %s", loc, resp.Status)
+	}
+
+	body := bufio.NewReader(io.LimitReader(resp.Body, sitemapMaxBytes))
+	if magic, _ := body.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+
+		body = bufio.NewReader(io.LimitReader(gz, sitemapMaxBytes))
+	}
+
+	var doc xmlDocument
+	if err := xml.NewDecoder(body).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+// addSitemapURLs queues the sitemap urls that are on the
+// crawled host, in scope and allowed by the robots.txt. The
+// urls blocked by the robots.txt are kept as error pages if
+// the error pages are being kept. Sitemap urls are crawled
+// like the seeds, at depth 0, so `MaxDepth` limits the clicks
+// from the nearest seed or sitemap url
+func (c *Crawler) addSitemapURLs(ctx context.Context, urls []url.URL) {
+	for _, u := range urls {
+		u = c.config.canonicalize(u)
+		if (u.Scheme != schemeHTTP && u.Scheme != schemeHTTPS) || u.Host != c.url.Host {
+			continue
+		}
+
+		if !c.inScope(&u) {
+			continue
+		}
+
+		key := u.String()
+		if allowed, rule := c.robotsAllowed(ctx, &u); !allowed {
+			if c.keepBlocked(u, rule) {
+				c.inSitemap[key] = true
+			}
+			continue
+		}
+
+		c.inSitemap[key] = true
+		if _, loaded := c.queued.LoadOrStore(key, true); loaded {
+			continue
+		}
+
+		c.addJob(link{URL: u, Depth: 0})
+	}
+}
+
+// setDiscovery records how each of the crawled pages was
+// found, once the linked from pages have been generated,
+// marking the seeds
+func (c *Crawler) setDiscovery() {
+	seeds := make(map[string]bool, len(c.seeds))
+	for _, seed := range c.seeds {
+		seeds[seed.String()] = true
+	}
+
+	for key, page := range c.SiteMap {
+		page.seed = seeds[key]
+		linked := len(page.LinkedFrom) > 0
+		switch {
+		case linked && c.inSitemap[key]:
+			page.DiscoveredBy = DiscoveryBoth
+		case c.inSitemap[key]:
+			page.DiscoveredBy = DiscoverySitemap
+		case linked:
+			page.DiscoveredBy = DiscoveryLinks
+		default:
+			page.DiscoveredBy = DiscoverySeed
+		}
+	}
+}
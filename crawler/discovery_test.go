@@ -0,0 +1,124 @@
+package crawler
+
+import (
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+)
+
+func TestCrawler_RunWithSitemaps(t *testing.T) {
+	s := test.NewSitemapServer()
+	s.WithSitemapRobots()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers: 1,
+		UserAgent:  "test-robot",
+		Sitemaps:   true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	urls := crawler.SiteMap.URLsCrawled()
+	sort.Strings(urls)
+	assert.Equal(t, []string{
+		s.URL + "/",
+		s.URL + "/about/",
+		s.URL + "/linked/",
+		s.URL + "/moved/",
+		s.URL + "/unlinked/",
+	}, urls)
+
+	assert.Equal(t, DiscoveryBoth, crawler.SiteMap[s.URL+"/"].DiscoveredBy)
+	assert.Equal(t, DiscoveryBoth, crawler.SiteMap[s.URL+"/linked/"].DiscoveredBy)
+	assert.Equal(t, DiscoverySitemap, crawler.SiteMap[s.URL+"/unlinked/"].DiscoveredBy)
+}
+
+func TestCrawler_RunWithSitemaps_Fallback(t *testing.T) {
+	s := test.NewSitemapServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		Sitemaps:        true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Len(t, crawler.SiteMap, 4)
+	assert.Equal(t, DiscoveryLinks, crawler.SiteMap[s.URL+"/"].DiscoveredBy)
+	assert.Equal(t, DiscoveryLinks, crawler.SiteMap[s.URL+"/linked/"].DiscoveredBy)
+	assert.Equal(t, DiscoverySitemap, crawler.SiteMap[s.URL+"/unlinked/"].DiscoveredBy)
+}
+
+func TestCrawler_RunWithoutSitemaps(t *testing.T) {
+	s := test.NewSitemapServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Len(t, crawler.SiteMap, 3)
+	assert.NotContains(t, crawler.SiteMap, s.URL+"/unlinked/")
+	assert.Equal(t, DiscoveryLinks, crawler.SiteMap[s.URL+"/"].DiscoveredBy)
+}
+
+func TestCrawler_RunWithSitemaps_MaxDepth(t *testing.T) {
+	s := test.NewSitemapDepthServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		Sitemaps:        true,
+		MaxDepth:        1,
+	})
+	err := crawler.Run()
+	assert.Equal(t, ErrTruncated, err)
+
+	depths := make(map[string]int)
+	for _, page := range crawler.SiteMap {
+		depths[page.URL.Path] = page.Depth
+	}
+	assert.Equal(t, map[string]int{
+		"/":        0,
+		"/1/":      1,
+		"/deep/":   0,
+		"/deep/1/": 1,
+	}, depths)
+}
+
+func TestCrawler_RunWithSitemaps_Seed(t *testing.T) {
+	s := test.NewSitemapServer()
+	s.Start()
+	defer s.Close()
+
+	u := *s.Url
+	u.Path = "/unlinked/"
+	crawler := NewWithConfig(u, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, DiscoverySeed, crawler.SiteMap[s.URL+"/unlinked/"].DiscoveredBy)
+}
@@ -0,0 +1,262 @@
+package crawler
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultDuplicateThreshold is the similarity pages need to
+	// be near duplicates when no threshold is set, 0.9 being
+	// at most 6 of the 64 bits of the SimHashes differing
+	DefaultDuplicateThreshold = 0.9
+
+	// shingleSize is the number of words in
+	// each shingle of the SimHash
+	shingleSize = 3
+)
+
+// DuplicateOptions are the options for finding
+// the duplicate pages of the sitemap
+type DuplicateOptions struct {
+	// Threshold is the similarity, above 0 and up to 1, of the
+	// SimHashes of two pages for them to be near duplicates, 1
+	// only groups exact duplicates. Defaults to
+	// `DefaultDuplicateThreshold` if 0
+	Threshold float64
+}
+
+// DuplicateCluster is a group of pages with the same, or nearly
+// the same, text. `Exact` is set if the text of every page is the
+// same, and `Similarity` is the lowest between any two pages.
+// `Canonicals` are the distinct canonical urls of the pages, an
+// empty one for pages without, the cluster being consistent when
+// they all point at the same canonical
+type DuplicateCluster struct {
+	URLs                []string `json:"urls"`
+	Exact               bool     `json:"exact"`
+	Similarity          float64  `json:"similarity"`
+	Canonicals          []string `json:"canonicals"`
+	CanonicalConsistent bool     `json:"canonical_consistent"`
+}
+
+// simHash passes back the 64 bit SimHash of the
+// shingles of the words, case insensitively
+func simHash(words []string) uint64 {
+	if len(words) == 0 {
+		return 0
+	}
+
+	size := shingleSize
+	if len(words) < size {
+		size = len(words)
+	}
+
+	var weights [64]int
+	for i := 0; i+size <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.ToLower(strings.Join(words[i:i+size], " "))))
+		sum := h.Sum64()
+
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var hash uint64
+	for bit := 0; bit < 64; bit++ {
+		if weights[bit] > 0 {
+			hash |= 1 << uint(bit)
+		}
+	}
+	return hash
+}
+
+// similarity passes back how similar the SimHashes are,
+// the share of the 64 bits that are the same
+func similarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/64
+}
+
+// Validate checks the threshold is above 0 and up to 1,
+// or 0 for the default
+func (o DuplicateOptions) Validate() error {
+	if o.Threshold < 0 || o.Threshold > 1 {
+		return fmt.Errorf("duplicate threshold %g is outside of (0, 1]", o.Threshold)
+	}
+	return nil
+}
+
+// threshold passes back the similarity threshold,
+// or an error if it's not valid
+func (o DuplicateOptions) threshold() (float64, error) {
+	if err := o.Validate(); err != nil {
+		return 0, err
+	}
+
+	if o.Threshold == 0 {
+		return DefaultDuplicateThreshold, nil
+	}
+	return o.Threshold, nil
+}
+
+// Duplicates groups the pages with duplicate or near duplicate
+// text into clusters, sorted by their first url. Only the html
+// pages that were crawled without errors or redirects and have
+// text are compared. Exact duplicates are grouped by their hash,
+// and near duplicates are only compared if a band of the bits of
+// their SimHashes is the same, see `nearDuplicateBands`
+func (s SiteMap) Duplicates(opts DuplicateOptions) ([]DuplicateCluster, error) {
+	threshold, err := opts.threshold()
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []*Page
+	for _, page := range s {
+		if !page.crawledOK() || page.Info.WordCount == 0 {
+			continue
+		}
+		pages = append(pages, page)
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].URL.String() < pages[j].URL.String()
+	})
+
+	parent := make([]int, len(pages))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		if a, b := find(i), find(j); a != b {
+			if b < a {
+				a, b = b, a
+			}
+			parent[b] = a
+		}
+	}
+
+	// only the first page of each exact duplicate
+	// is compared for near duplicates
+	var distinct []int
+	byHash := make(map[string]int)
+	for i, page := range pages {
+		if first, ok := byHash[page.Info.ContentHash]; ok {
+			union(first, i)
+			continue
+		}
+		byHash[page.Info.ContentHash] = i
+		distinct = append(distinct, i)
+	}
+
+	if threshold < 1 {
+		for _, band := range nearDuplicateBands(threshold) {
+			buckets := make(map[uint64][]int)
+			for _, i := range distinct {
+				key := pages[i].Info.SimHash & band
+				buckets[key] = append(buckets[key], i)
+			}
+
+			for _, bucket := range buckets {
+				for x, i := range bucket {
+					for _, j := range bucket[x+1:] {
+						if find(i) != find(j) && similarity(pages[i].Info.SimHash, pages[j].Info.SimHash) >= threshold {
+							union(i, j)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	groups := make(map[int][]*Page)
+	for i, page := range pages {
+		root := find(i)
+		groups[root] = append(groups[root], page)
+	}
+
+	var clusters []DuplicateCluster
+	for _, group := range groups {
+		if len(group) > 1 {
+			clusters = append(clusters, newDuplicateCluster(group))
+		}
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].URLs[0] < clusters[j].URLs[0]
+	})
+
+	return clusters, nil
+}
+
+// nearDuplicateBands splits the 64 bits of the SimHashes into the
+// masks of one more band than the bits that can differ at the
+// threshold. SimHashes within the threshold then have at least
+// one band the same, so only the pages sharing a band need to
+// be compared. Lower thresholds make for narrower bands, and
+// more pages to compare
+func nearDuplicateBands(threshold float64) []uint64 {
+	differ := int((1-threshold)*64 + 1e-9)
+	count := differ + 1
+	if count > 64 {
+		count = 64
+	}
+
+	bands := make([]uint64, count)
+	for i := range bands {
+		from, to := i*64/count, (i+1)*64/count
+		for bit := from; bit < to; bit++ {
+			bands[i] |= 1 << uint(bit)
+		}
+	}
+	return bands
+}
+
+// newDuplicateCluster passes back the cluster
+// of the pages, sorted by url
+func newDuplicateCluster(pages []*Page) DuplicateCluster {
+	cluster := DuplicateCluster{Exact: true, Similarity: 1}
+	canonicals := make(map[string]bool)
+	hashes := make(map[string]bool)
+	var distinct []uint64
+	for _, page := range pages {
+		cluster.URLs = append(cluster.URLs, page.URL.String())
+		if !canonicals[page.Info.Canonical] {
+			canonicals[page.Info.Canonical] = true
+			cluster.Canonicals = append(cluster.Canonicals, page.Info.Canonical)
+		}
+
+		if !hashes[page.Info.ContentHash] {
+			hashes[page.Info.ContentHash] = true
+			distinct = append(distinct, page.Info.SimHash)
+		}
+	}
+
+	cluster.Exact = len(distinct) == 1
+	for i, a := range distinct {
+		for _, b := range distinct[i+1:] {
+			if sim := similarity(a, b); sim < cluster.Similarity {
+				cluster.Similarity = sim
+			}
+		}
+	}
+
+	sort.Strings(cluster.Canonicals)
+	cluster.CanonicalConsistent = len(cluster.Canonicals) == 1 && cluster.Canonicals[0] != ""
+
+	return cluster
+}
@@ -0,0 +1,124 @@
+package crawler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const duplicateArticle = `The quick brown fox jumps over the lazy dog while the farmer
+watches from the porch of the old red barn. Later that evening the fox returns
+to the field looking for the chickens that the farmer keeps in a small coop
+behind the house, but the dog is awake now and chases the fox back into the
+woods where it hides until morning comes and the whole thing starts again.
+In the spring the farmer mends the fence along the north side of the field and
+plants rows of corn and beans that grow tall over the summer months. The
+children from the village come by on the weekends to help with the harvest,
+carrying baskets of apples down to the market in town where the baker buys
+them for the pies he sells at the autumn fair. When the first snow falls the
+animals are brought into the barn and the farmer spends the long winter nights
+by the fire reading about faraway places he has never visited and probably
+never will, content with the quiet life on the farm.`
+
+// newDuplicatePage passes back a crawled page
+// with the text and canonical url
+func newDuplicatePage(t *testing.T, s SiteMap, u, text, canonical string) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := &Page{URL: *parsed, Info: PageInfo{Canonical: canonical}}
+	info := &infoParser{info: &page.Info, words: strings.Fields(text)}
+	info.finish()
+	s[u] = page
+}
+
+func TestSiteMap_Duplicates(t *testing.T) {
+	s := make(SiteMap)
+	newDuplicatePage(t, s, "https://example.com/a/", duplicateArticle, "https://example.com/a/")
+	newDuplicatePage(t, s, "https://example.com/b/", duplicateArticle, "https://example.com/a/")
+	newDuplicatePage(t, s, "https://example.com/c/", duplicateArticle, "https://example.com/a/")
+	newDuplicatePage(t, s, "https://example.com/d/", strings.Replace(duplicateArticle, "small", "tiny", 1), "")
+	newDuplicatePage(t, s, "https://example.com/e/", "A different page about something else entirely.", "")
+	newDuplicatePage(t, s, "https://example.com/f/", "", "")
+
+	s["https://example.com/b/"].Response.ErrorCategory = ErrorClient
+
+	clusters, err := s.Duplicates(DuplicateOptions{})
+	assert.NoError(t, err)
+	if assert.Len(t, clusters, 1) {
+		cluster := clusters[0]
+		assert.Equal(t, []string{
+			"https://example.com/a/",
+			"https://example.com/c/",
+			"https://example.com/d/",
+		}, cluster.URLs)
+		assert.False(t, cluster.Exact)
+		assert.True(t, cluster.Similarity >= DefaultDuplicateThreshold, cluster.Similarity)
+		assert.True(t, cluster.Similarity < 1, cluster.Similarity)
+		assert.Equal(t, []string{"", "https://example.com/a/"}, cluster.Canonicals)
+		assert.False(t, cluster.CanonicalConsistent)
+	}
+
+	clusters, err = s.Duplicates(DuplicateOptions{Threshold: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []DuplicateCluster{
+		{
+			URLs:                []string{"https://example.com/a/", "https://example.com/c/"},
+			Exact:               true,
+			Similarity:          1,
+			Canonicals:          []string{"https://example.com/a/"},
+			CanonicalConsistent: true,
+		},
+	}, clusters)
+
+	clusters, err = make(SiteMap).Duplicates(DuplicateOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, clusters)
+
+	for _, threshold := range []float64{-0.5, 1.01, 90} {
+		_, err = s.Duplicates(DuplicateOptions{Threshold: threshold})
+		assert.Error(t, err, threshold)
+	}
+}
+
+func TestNearDuplicateBands(t *testing.T) {
+	for _, threshold := range []float64{0.99, 0.95, 0.9, 0.5, 0.01} {
+		bands := nearDuplicateBands(threshold)
+		differ := int((1-threshold)*64 + 1e-9)
+		if differ < 63 {
+			assert.Len(t, bands, differ+1, threshold)
+		}
+
+		var all uint64
+		for _, band := range bands {
+			assert.NotZero(t, band)
+			assert.Zero(t, all&band, threshold)
+			all |= band
+		}
+		assert.Equal(t, ^uint64(0), all, threshold)
+	}
+
+	// hashes 6 bits apart always share a band at 0.9
+	bands := nearDuplicateBands(0.9)
+	var a, b uint64 = 0, 0x8080808080800000
+	shared := false
+	for _, band := range bands {
+		if a&band == b&band {
+			shared = true
+		}
+	}
+	assert.True(t, shared)
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, similarity(0, 0))
+	assert.Equal(t, 0.0, similarity(0, ^uint64(0)))
+	assert.Equal(t, 1-3.0/64, similarity(0, 7))
+
+	words := strings.Fields(duplicateArticle)
+	assert.Equal(t, simHash(words), simHash(strings.Fields(strings.ToUpper(duplicateArticle))))
+	assert.NotEqual(t, simHash(words), simHash(words[:len(words)/2]))
+}
@@ -0,0 +1,120 @@
+package crawler
+
+import (
+	"strings"
+)
+
+const (
+	tokenImage = "img"
+	attrAlt    = "alt"
+	attrTitle  = "title"
+	attrTarget = "target"
+)
+
+// Region is the part of the page a link was found
+// in, from the sectioning elements around it
+type Region string
+
+// The regions of a page, a link outside of
+// any of them has no region
+const (
+	RegionNav    Region = "nav"
+	RegionHeader Region = "header"
+	RegionFooter Region = "footer"
+	RegionMain   Region = "main"
+	RegionAside  Region = "aside"
+)
+
+// regions are the elements that start a region
+var regions = map[string]Region{
+	"nav":    RegionNav,
+	"header": RegionHeader,
+	"footer": RegionFooter,
+	"main":   RegionMain,
+	"aside":  RegionAside,
+}
+
+// Edge is a link from a page along with the metadata of
+// the element it was found in. `Count` is the number of
+// times the page links to the url, the metadata being
+// from the first of them
+type Edge struct {
+	URL        string   `json:"url"`
+	Type       LinkType `json:"type"`
+	Text       string   `json:"text"`
+	Rel        []string `json:"rel,omitempty"`
+	Title      string   `json:"title,omitempty"`
+	Target     string   `json:"target,omitempty"`
+	Image      bool     `json:"image"`
+	Region     Region   `json:"region,omitempty"`
+	Count      int      `json:"count"`
+	NoFollow   bool     `json:"nofollow"`
+	OutOfScope bool     `json:"out_of_scope"`
+	Asset      bool     `json:"asset"`
+}
+
+// Edges passes back the links as edges of the link graph
+func (l links) Edges() []Edge {
+	edges := make([]Edge, 0, len(l))
+	for _, link := range l {
+		edges = append(edges, Edge{
+			URL:        link.URL.String(),
+			Type:       link.Type,
+			Text:       link.Text,
+			Rel:        link.Rel,
+			Title:      link.Title,
+			Target:     link.Target,
+			Image:      link.Image,
+			Region:     link.Region,
+			Count:      link.Count,
+			NoFollow:   link.NoFollow,
+			OutOfScope: link.OutOfScope,
+			Asset:      link.Asset,
+		})
+	}
+	return edges
+}
+
+// regionStack is the regions the tokenizer is inside of,
+// the innermost being last
+type regionStack []Region
+
+// push enters the region if the element starts one
+func (s *regionStack) push(element string) {
+	if region, ok := regions[element]; ok {
+		*s = append(*s, region)
+	}
+}
+
+// pop leaves the innermost region started by the element
+func (s *regionStack) pop(element string) {
+	region, ok := regions[element]
+	if !ok {
+		return
+	}
+
+	for i := len(*s) - 1; i >= 0; i-- {
+		if (*s)[i] == region {
+			*s = append((*s)[:i], (*s)[i+1:]...)
+			return
+		}
+	}
+}
+
+// current passes back the innermost region
+func (s regionStack) current() Region {
+	if len(s) == 0 {
+		return ""
+	}
+	return s[len(s)-1]
+}
+
+// relValues splits the rel attribute into its
+// lowercased link types
+func relValues(rel string) []string {
+	var values []string
+	for _, v := range strings.Fields(rel) {
+		values = append(values, strings.ToLower(v))
+	}
+	return values
+}
@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"encoding/json"
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCrawler_RunWithEdges(t *testing.T) {
+	s := test.NewEdgeServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := New(*s.Url, true, 1, "test-robot")
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	index, ok := crawler.SiteMap[s.URL+"/"]
+	if !ok {
+		t.Fatal("should exist")
+	}
+
+	assert.Equal(t, []Edge{
+		{
+			URL:      s.URL + "/a/",
+			Type:     LinkAnchor,
+			Text:     "Nav A",
+			Rel:      []string{"nofollow", "noopener"},
+			Title:    "Page A",
+			Region:   RegionNav,
+			Count:    1,
+			NoFollow: true,
+		},
+		{
+			URL:    s.URL + "/b/",
+			Type:   LinkAnchor,
+			Text:   "Main B",
+			Target: "_blank",
+			Region: RegionMain,
+			Count:  3,
+		},
+		{
+			URL:    s.URL + "/c/",
+			Type:   LinkAnchor,
+			Text:   "C image",
+			Image:  true,
+			Region: RegionMain,
+			Count:  1,
+		},
+		{
+			URL:    s.URL + "/d/",
+			Type:   LinkAnchor,
+			Text:   "Footer D",
+			Region: RegionFooter,
+			Count:  1,
+		},
+		{
+			URL:   s.URL + "/e/",
+			Type:  LinkAnchor,
+			Text:  "Body E",
+			Count: 1,
+		},
+	}, index.Links.Edges())
+
+	js, err := json.Marshal(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out struct {
+		Edges []Edge `json:"edges"`
+	}
+	if err := json.Unmarshal(js, &out); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, index.Links.Edges(), out.Edges)
+}
+
+func TestRegionStack(t *testing.T) {
+	var s regionStack
+	assert.Equal(t, Region(""), s.current())
+
+	s.push("main")
+	s.push("div")
+	s.push("aside")
+	assert.Equal(t, RegionAside, s.current())
+
+	s.pop("div")
+	s.pop("aside")
+	assert.Equal(t, RegionMain, s.current())
+
+	s.pop("main")
+	s.pop("main")
+	assert.Equal(t, Region(""), s.current())
+}
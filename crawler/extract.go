@@ -0,0 +1,225 @@
+package crawler
+
+import (
+	"context"
+	"golang.org/x/net/html"
+	"net/url"
+	"strings"
+)
+
+// LinkType is the type of edge a link creates
+// between the page and what it links to
+type LinkType string
+
+// The types of link created by `DefaultLinkRules`
+const (
+	LinkAnchor     LinkType = "anchor"
+	LinkArea       LinkType = "area"
+	LinkIframe     LinkType = "iframe"
+	LinkNext       LinkType = "next"
+	LinkPrev       LinkType = "prev"
+	LinkAlternate  LinkType = "alternate"
+	LinkCanonical  LinkType = "canonical"
+	LinkForm       LinkType = "form"
+	LinkRefresh    LinkType = "refresh"
+	LinkImage      LinkType = "image"
+	LinkScript     LinkType = "script"
+	LinkStylesheet LinkType = "stylesheet"
+	LinkMedia      LinkType = "media"
+)
+
+// LinkRule is where links are extracted from, the attribute of
+// the element holding the url, whether the link is followed and
+// crawled as a page and the type of link it creates. Links that
+// aren't followed are assets, only checked if `CheckAssets` is set
+type LinkRule struct {
+	Element string
+	Attr    string
+	Follow  bool
+	Type    LinkType
+
+	// Rel limits the rule to the elements with
+	// one of the rel values, e.g. `next`
+	Rel []string
+
+	// Match is an extra check of the attributes of the
+	// element, e.g. only forms that GET, nil matches all
+	Match func(attrs map[string]string) bool
+
+	// Parse passes back the urls in the attribute value,
+	// e.g. for a `srcset`, nil uses the value as the url
+	Parse func(value string) []string
+}
+
+// DefaultLinkRules are the rules used when `LinkRules` isn't set
+var DefaultLinkRules = []LinkRule{
+	{Element: "a", Attr: "href", Follow: true, Type: LinkAnchor},
+	{Element: "area", Attr: "href", Follow: true, Type: LinkArea},
+	{Element: "iframe", Attr: "src", Follow: true, Type: LinkIframe},
+	{Element: "link", Attr: "href", Follow: true, Type: LinkNext, Rel: []string{"next"}},
+	{Element: "link", Attr: "href", Follow: true, Type: LinkPrev, Rel: []string{"prev", "previous"}},
+	{Element: "link", Attr: "href", Follow: true, Type: LinkAlternate, Rel: []string{"alternate"}},
+	{Element: "link", Attr: "href", Follow: true, Type: LinkCanonical, Rel: []string{"canonical"}},
+	{Element: "form", Attr: "action", Follow: true, Type: LinkForm, Match: formGet},
+	{Element: "meta", Attr: "content", Follow: true, Type: LinkRefresh, Match: metaRefresh, Parse: parseRefresh},
+	{Element: "img", Attr: "src", Type: LinkImage},
+	{Element: "img", Attr: "srcset", Type: LinkImage, Parse: parseSrcset},
+	{Element: "script", Attr: "src", Type: LinkScript},
+	{Element: "link", Attr: "href", Type: LinkStylesheet, Rel: []string{"stylesheet"}},
+	{Element: "source", Attr: "src", Type: LinkMedia},
+	{Element: "source", Attr: "srcset", Type: LinkImage, Parse: parseSrcset},
+	{Element: "video", Attr: "src", Type: LinkMedia},
+	{Element: "video", Attr: "poster", Type: LinkImage},
+}
+
+// formGet matches the forms that are submitted with a GET
+func formGet(attrs map[string]string) bool {
+	method := strings.TrimSpace(attrs["method"])
+	return method == "" || strings.EqualFold(method, "get")
+}
+
+// metaRefresh matches the `<meta http-equiv="refresh">` tags
+func metaRefresh(attrs map[string]string) bool {
+	return strings.EqualFold(strings.TrimSpace(attrs["http-equiv"]), "refresh")
+}
+
+// parseRefresh passes back the url of a meta refresh,
+// e.g. `5; url=/next/`
+func parseRefresh(value string) []string {
+	i := strings.IndexAny(value, ";,")
+	if i < 0 {
+		return nil
+	}
+
+	value = strings.TrimSpace(value[i+1:])
+	if len(value) < 4 || !strings.EqualFold(value[:3], "url") {
+		return nil
+	}
+
+	value = strings.TrimSpace(value[3:])
+	if !strings.HasPrefix(value, "=") {
+		return nil
+	}
+
+	value = strings.Trim(strings.TrimSpace(value[1:]), `'"`)
+	if value == "" {
+		return nil
+	}
+
+	return []string{value}
+}
+
+// parseSrcset passes back the urls of the image candidates,
+// e.g. `small.jpg 480w, large.jpg 1080w`
+func parseSrcset(value string) []string {
+	var urls []string
+	for _, candidate := range strings.Split(value, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) > 0 {
+			urls = append(urls, fields[0])
+		}
+	}
+	return urls
+}
+
+// linkRules passes back the link rules of the crawl,
+// leaving out the asset rules unless assets are checked
+func (c Config) linkRules() []LinkRule {
+	rules := c.LinkRules
+	if rules == nil {
+		rules = DefaultLinkRules
+	}
+
+	if c.CheckAssets {
+		return rules
+	}
+
+	var followed []LinkRule
+	for _, rule := range rules {
+		if rule.Follow {
+			followed = append(followed, rule)
+		}
+	}
+	return followed
+}
+
+// matches checks if the rule applies to the element
+func (r LinkRule) matches(element string, attrs map[string]string) bool {
+	if !strings.EqualFold(r.Element, element) {
+		return false
+	}
+
+	if len(r.Rel) > 0 {
+		found := false
+		for _, rel := range r.Rel {
+			if hasRel(attrs[attrRel], rel) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return r.Match == nil || r.Match(attrs)
+}
+
+// extractLinks stores the links of the element matched by the
+// link rules, passing back the index of the last link stored,
+// or -1 if none were. Links already on the page are counted
+// rather than stored again
+func (p *Page) extractLinks(ctx context.Context, token html.Token, base *url.URL, region Region, cache *map[string]bool) int {
+	attrs := make(map[string]string, len(token.Attr))
+	for _, attr := range token.Attr {
+		if _, ok := attrs[attr.Key]; !ok {
+			attrs[attr.Key] = attr.Val
+		}
+	}
+
+	noFollow := p.NoFollow || hasRel(attrs[attrRel], relNoFollow)
+	last := -1
+	for _, rule := range p.crawler.rules {
+		value, ok := attrs[rule.Attr]
+		if !ok || !rule.matches(token.Data, attrs) {
+			continue
+		}
+
+		values := []string{value}
+		if rule.Parse != nil {
+			values = rule.Parse(value)
+		}
+
+		for _, v := range values {
+			linkURL, err := p.getLinkURL(base, v)
+			if err != nil {
+				continue
+			}
+
+			link, ok := p.parseLink(ctx, linkURL, p.URL, noFollow && rule.Follow, !rule.Follow, cache)
+			if !ok {
+				if i, stored := p.linkIndex[link.seenKey()]; stored {
+					p.Links[i].Count++
+				}
+				continue
+			}
+
+			link.Type = rule.Type
+			link.Asset = !rule.Follow
+			link.Rel = relValues(attrs[attrRel])
+			link.Title = attrs[attrTitle]
+			link.Target = attrs[attrTarget]
+			link.Region = region
+			link.Count = 1
+			p.Links = append(p.Links, link)
+			last = len(p.Links) - 1
+
+			if p.linkIndex == nil {
+				p.linkIndex = make(map[string]int)
+			}
+			p.linkIndex[link.seenKey()] = last
+		}
+	}
+
+	return last
+}
@@ -0,0 +1,215 @@
+package crawler
+
+import (
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCrawler_RunWithLinkRules(t *testing.T) {
+	s := test.NewExtractServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := New(*s.Url, true, 1, "test-robot")
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	types := make(map[string]LinkType)
+	for _, l := range crawler.SiteMap[s.URL+"/"].Links {
+		assert.False(t, l.Asset, l.URL.Path)
+		types[l.URL.Path] = l.Type
+	}
+
+	assert.Equal(t, map[string]LinkType{
+		"/refresh/":   LinkRefresh,
+		"/next/":      LinkNext,
+		"/canonical/": LinkCanonical,
+		"/alternate/": LinkAlternate,
+		"/area/":      LinkArea,
+		"/frame/":     LinkIframe,
+		"/search/":    LinkForm,
+	}, types)
+	assert.Len(t, crawler.SiteMap, 8)
+}
+
+func TestCrawler_RunWithCheckAssets(t *testing.T) {
+	s := test.NewExtractServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		KeepErrorPages:  true,
+		CheckAssets:     true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assets := map[string]LinkType{
+		"/style.css":    LinkStylesheet,
+		"/app.js":       LinkScript,
+		"/image.png":    LinkImage,
+		"/image-2x.png": LinkImage,
+		"/image-3x.png": LinkImage,
+		"/missing.png":  LinkImage,
+		"/movie.mp4":    LinkMedia,
+		"/movie.webm":   LinkMedia,
+	}
+
+	found := make(map[string]LinkType)
+	for _, l := range crawler.SiteMap[s.URL+"/"].Links {
+		if l.Asset {
+			found[l.URL.Path] = l.Type
+		}
+	}
+	assert.Equal(t, assets, found)
+
+	for path := range assets {
+		page, ok := crawler.SiteMap[s.URL+path]
+		if !ok {
+			t.Errorf("%s should exist", path)
+			continue
+		}
+
+		assert.True(t, page.Asset, path)
+		assert.Empty(t, page.Links, path)
+		if path != "/missing.png" {
+			assert.Empty(t, page.Response.ErrorCategory, path)
+		}
+	}
+
+	broken := crawler.SiteMap.BrokenLinks()
+	if assert.Len(t, broken, 1) {
+		assert.Equal(t, s.URL+"/missing.png", broken[0].Target)
+		assert.Equal(t, ErrorClient, broken[0].ErrorCategory)
+	}
+}
+
+func TestCrawler_RunWithCheckAssets_OtherHost(t *testing.T) {
+	cdn := test.NewCDNServer()
+	cdn.Start()
+	defer cdn.Close()
+
+	s := test.NewCDNSiteServer(cdn.URL)
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:     1,
+		MaxPages:       2,
+		KeepErrorPages: true,
+		CheckAssets:    true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	var urls []string
+	for key := range crawler.SiteMap {
+		urls = append(urls, key)
+	}
+	assert.ElementsMatch(t, []string{
+		s.URL + "/",
+		s.URL + "/1/",
+		cdn.URL + "/style.css",
+		cdn.URL + "/logo.png",
+		cdn.URL + "/missing.png",
+	}, urls)
+	assert.False(t, crawler.truncated)
+
+	for _, path := range []string{"/style.css", "/logo.png", "/missing.png"} {
+		assert.True(t, crawler.SiteMap[cdn.URL+path].Asset, path)
+	}
+
+	broken := crawler.SiteMap.BrokenLinks()
+	if assert.Len(t, broken, 1) {
+		assert.Equal(t, cdn.URL+"/missing.png", broken[0].Target)
+	}
+
+	blocked := crawler.SiteMap[s.URL+"/"].BlockedLinks
+	if assert.Len(t, blocked, 1) {
+		assert.Equal(t, cdn.URL+"/2/blocked.png", blocked[0].URL)
+	}
+}
+
+func TestCrawler_RunWithLinkOutcomes(t *testing.T) {
+	s := test.NewLinkOutcomeServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		CheckAssets:     true,
+		MaxDepth:        1,
+	})
+	err := crawler.Run()
+	assert.Equal(t, ErrTruncated, err)
+
+	var urls []string
+	for key := range crawler.SiteMap {
+		urls = append(urls, key)
+	}
+	assert.ElementsMatch(t, []string{
+		s.URL + "/",
+		s.URL + "/later/",
+		s.URL + "/page/",
+		s.URL + "/image.png",
+	}, urls)
+
+	var later []Edge
+	for _, edge := range crawler.SiteMap[s.URL+"/"].Links.Edges() {
+		if edge.URL == s.URL+"/later/" {
+			later = append(later, edge)
+		}
+	}
+	if assert.Len(t, later, 2) {
+		assert.True(t, later[0].NoFollow)
+		assert.Equal(t, 1, later[0].Count)
+		assert.False(t, later[1].NoFollow)
+		assert.Equal(t, 2, later[1].Count)
+	}
+
+	assert.Equal(t, 2, crawler.SiteMap[s.URL+"/image.png"].Depth)
+}
+
+func TestCrawler_RunWithCustomLinkRules(t *testing.T) {
+	s := test.NewExtractServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		LinkRules: []LinkRule{
+			{Element: "iframe", Attr: "src", Follow: true, Type: LinkIframe},
+		},
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, []string{"/frame/"}, crawler.SiteMap[s.URL+"/"].Links.Paths())
+	assert.Len(t, crawler.SiteMap, 2)
+}
+
+func TestParseRefresh(t *testing.T) {
+	assert.Equal(t, []string{"/next/"}, parseRefresh("5; url=/next/"))
+	assert.Equal(t, []string{"/next/"}, parseRefresh(`0;URL="/next/"`))
+	assert.Equal(t, []string{"/next/"}, parseRefresh("0, url = /next/"))
+	assert.Empty(t, parseRefresh("30"))
+	assert.Empty(t, parseRefresh("5; /next/"))
+}
+
+func TestParseSrcset(t *testing.T) {
+	assert.Equal(t, []string{"small.jpg", "large.jpg"}, parseSrcset("small.jpg 480w,  large.jpg 1080w"))
+	assert.Equal(t, []string{"image.png"}, parseSrcset("image.png"))
+}
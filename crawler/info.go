@@ -0,0 +1,206 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"golang.org/x/net/html"
+	"net/url"
+	"strings"
+)
+
+const (
+	tokenTitle  = "title"
+	tokenHTML   = "html"
+	tokenLink   = "link"
+	tokenScript = "script"
+	tokenStyle  = "style"
+
+	attrLang = "lang"
+
+	metaDescription = "description"
+	relCanonical    = "canonical"
+	relAlternate    = "alternate"
+	attrHreflang    = "hreflang"
+)
+
+// headings are the heading elements that are recorded
+var headings = map[string]bool{"h1": true, "h2": true, "h3": true}
+
+// PageInfo is the on-page metadata of a page, `Canonical` and the
+// `Hreflang` urls are resolved against the page url and normalized
+// like its links, `ContentHash` is the sha256 of the words of the
+// page text and `SimHash` the near duplicate signature of them,
+// see `SiteMap.Duplicates`
+type PageInfo struct {
+	Title            string     `json:"title"`
+	MetaDescription  string     `json:"meta_description"`
+	Canonical        string     `json:"canonical"`
+	Hreflang         []Hreflang `json:"hreflang"`
+	Lang             string     `json:"lang"`
+	H1               []string   `json:"h1"`
+	H2               []string   `json:"h2"`
+	H3               []string   `json:"h3"`
+	WordCount        int        `json:"word_count"`
+	ImagesMissingAlt int        `json:"images_missing_alt"`
+	ContentHash      string     `json:"content_hash"`
+	SimHash          uint64     `json:"simhash"`
+}
+
+// Hreflang is an alternate language version of a page, from a
+// `<link rel="alternate" hreflang="...">` tag
+type Hreflang struct {
+	Lang string `json:"lang"`
+	URL  string `json:"url"`
+}
+
+// infoParser collects the `PageInfo` from the tokens
+// of the page as it's tokenized
+type infoParser struct {
+	info *PageInfo
+
+	// canonicalize normalizes the canonical and
+	// hreflang urls, nil leaves them as they are
+	canonicalize func(u url.URL) url.URL
+
+	// words are the words of the page text, leaving
+	// out the title, scripts and styles
+	words []string
+
+	// capture is the element whose text is being
+	// read into text, empty when not reading
+	capture string
+	text    strings.Builder
+
+	hasTitle       bool
+	hasDescription bool
+	hasCanonical   bool
+
+	// skip is the script or style element being skipped
+	skip string
+}
+
+// token reads the metadata from the token, the canonical
+// url is resolved against the base
+func (i *infoParser) token(t html.TokenType, token html.Token, base *url.URL) {
+	name := token.Data
+	switch t {
+	case html.TextToken:
+		if i.skip != "" {
+			return
+		}
+		if i.capture != "" {
+			i.text.WriteString(token.Data)
+		}
+		if i.capture != tokenTitle {
+			i.words = append(i.words, strings.Fields(token.Data)...)
+		}
+	case html.EndTagToken:
+		if name == i.skip {
+			i.skip = ""
+		}
+		if name == i.capture {
+			i.end()
+		}
+	case html.StartTagToken, html.SelfClosingTagToken:
+		i.start(t, name, token.Attr, base)
+	}
+}
+
+// start reads the metadata of the element
+func (i *infoParser) start(t html.TokenType, name string, attrs []html.Attribute, base *url.URL) {
+	attr := func(key string) (string, bool) {
+		for _, a := range attrs {
+			if a.Key == key {
+				return a.Val, true
+			}
+		}
+		return "", false
+	}
+
+	switch {
+	case (name == tokenScript || name == tokenStyle) && t == html.StartTagToken:
+		i.skip = name
+	case name == tokenHTML:
+		if lang, ok := attr(attrLang); ok && i.info.Lang == "" {
+			i.info.Lang = strings.TrimSpace(lang)
+		}
+	case name == tokenTitle && !i.hasTitle && t == html.StartTagToken:
+		i.capture = name
+		i.text.Reset()
+	case headings[name] && i.capture == "" && t == html.StartTagToken:
+		i.capture = name
+		i.text.Reset()
+	case name == tokenMeta && !i.hasDescription:
+		if n, _ := attr(attrName); strings.EqualFold(strings.TrimSpace(n), metaDescription) {
+			content, _ := attr(attrContent)
+			i.info.MetaDescription = strings.Join(strings.Fields(content), " ")
+			i.hasDescription = true
+		}
+	case name == tokenLink:
+		rel, _ := attr(attrRel)
+		href, ok := attr(attrHref)
+		if !ok {
+			return
+		}
+
+		lang, hasLang := attr(attrHreflang)
+		switch {
+		case hasRel(rel, relCanonical) && !i.hasCanonical:
+			if canonical, ok := i.resolve(base, href); ok {
+				i.info.Canonical = canonical
+				i.hasCanonical = true
+			}
+		case hasRel(rel, relAlternate) && hasLang && strings.TrimSpace(lang) != "":
+			if alternate, ok := i.resolve(base, href); ok {
+				i.info.Hreflang = append(i.info.Hreflang, Hreflang{
+					Lang: strings.TrimSpace(lang),
+					URL:  alternate,
+				})
+			}
+		}
+	case name == tokenImage:
+		if _, ok := attr(attrAlt); !ok {
+			i.info.ImagesMissingAlt++
+		}
+	}
+}
+
+// resolve passes back the url of the href
+// resolved against the base and normalized
+func (i *infoParser) resolve(base *url.URL, href string) (string, bool) {
+	u, err := base.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", false
+	}
+
+	if i.canonicalize != nil {
+		*u = i.canonicalize(*u)
+	}
+	return u.String(), true
+}
+
+// end stores the text read for the element being captured
+func (i *infoParser) end() {
+	text := strings.Join(strings.Fields(i.text.String()), " ")
+	switch i.capture {
+	case tokenTitle:
+		i.info.Title = text
+		i.hasTitle = true
+	case "h1":
+		i.info.H1 = append(i.info.H1, text)
+	case "h2":
+		i.info.H2 = append(i.info.H2, text)
+	case "h3":
+		i.info.H3 = append(i.info.H3, text)
+	}
+	i.capture = ""
+}
+
+// finish counts the words of the page and hashes them
+func (i *infoParser) finish() {
+	i.info.WordCount = len(i.words)
+
+	sum := sha256.Sum256([]byte(strings.Join(i.words, " ")))
+	i.info.ContentHash = hex.EncodeToString(sum[:])
+	i.info.SimHash = simHash(i.words)
+}
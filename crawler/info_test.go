@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func TestCrawler_RunWithPageInfo(t *testing.T) {
+	s := test.NewInfoServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := New(*s.Url, true, 1, "test-robot")
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	page, ok := crawler.SiteMap[s.URL+"/"]
+	if !ok {
+		t.Fatal("should exist")
+	}
+
+	sum := sha256.Sum256([]byte("Main heading Some words on the page. First Second Third"))
+	assert.Equal(t, PageInfo{
+		Title:           "Info Page",
+		MetaDescription: "The page description",
+		Canonical:       s.URL + "/canonical/",
+		Hreflang: []Hreflang{
+			{Lang: "fr", URL: s.URL + "/fr/"},
+			{Lang: "x-default", URL: s.URL + "/"},
+		},
+		Lang:             "en-GB",
+		H1:               []string{"Main heading"},
+		H2:               []string{"First", "Second"},
+		H3:               []string{"Third"},
+		WordCount:        10,
+		ImagesMissingAlt: 2,
+		ContentHash:      hex.EncodeToString(sum[:]),
+		SimHash:          page.Info.SimHash,
+	}, page.Info)
+	assert.NotZero(t, page.Info.SimHash)
+}
+
+func TestSiteMap_WriteCSV(t *testing.T) {
+	s := make(SiteMap)
+
+	home, _ := url.Parse("https://example.com/")
+	s[home.String()] = &Page{
+		URL:          *home,
+		Response:     Response{StatusCode: 200},
+		DiscoveredBy: DiscoverySeed,
+		Info: PageInfo{
+			Title:       "Home, sweet home",
+			H2:          []string{"One", "Two"},
+			WordCount:   3,
+			ContentHash: "abc",
+		},
+	}
+
+	old, _ := url.Parse("https://example.com/old/")
+	s[old.String()] = &Page{
+		URL:         *old,
+		Depth:       1,
+		IsRedirect:  true,
+		RedirectsTo: home,
+		Response:    Response{StatusCode: 200},
+		LinkedFrom:  links{{URL: *home}},
+	}
+
+	buf := &bytes.Buffer{}
+	err := s.WriteCSV(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "url,status_code,error_category,depth,redirects_to,discovered_by,noindex,nofollow,"+
+		"title,meta_description,canonical,lang,h1,h2,h3,word_count,images_missing_alt,content_hash,links,linked_from\n"+
+		"https://example.com/,200,,0,,seed,false,false,\"Home, sweet home\",,,,,One | Two,,3,0,abc,0,0\n"+
+		"https://example.com/old/,200,,1,https://example.com/,,false,false,,,,,,,,0,0,,0,1\n", buf.String())
+}
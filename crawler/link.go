@@ -5,14 +5,44 @@ import (
 )
 
 // link small struct to store the crawling url/link
-// and a bool to tell if the link has been crawled
+// and a bool to tell if the link has been crawled, also
+// stores the depth the link was found at and if the link
+// was left out of the crawl by the scope rules or for being
+// nofollow. The `URL` is the canonical url of the link,
+// `Text` its anchor text and `Type` the rule it came from,
+// `Asset` being set for the links that aren't followed.
+// The rest is the metadata of the element, see `Edge`
 type link struct {
-	URL     url.URL
-	Crawled bool
+	URL        url.URL
+	Text       string
+	Crawled    bool
+	Depth      int
+	OutOfScope bool
+	NoFollow   bool
+	Type       LinkType
+	Asset      bool
+	Rel        []string
+	Title      string
+	Target     string
+	Image      bool
+	Region     Region
+	Count      int
+
+	// outcome is what became of the link when it was parsed
+	outcome linkOutcome
 }
 
 type links []link
 
+// URLs returns the canonical urls of the links
+func (l links) URLs() []string {
+	var urls []string
+	for _, link := range l {
+		urls = append(urls, link.URL.String())
+	}
+	return urls
+}
+
 func (l links) Paths() []string {
 	var links []string
 	for _, link := range l{
@@ -20,3 +50,15 @@ func (l links) Paths() []string {
 	}
 	return links
 }
+
+// OutOfScopePaths returns the paths of the links that
+// were left out of the crawl by the scope rules
+func (l links) OutOfScopePaths() []string {
+	var paths []string
+	for _, link := range l {
+		if link.OutOfScope {
+			paths = append(paths, link.URL.Path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,197 @@
+package crawler
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+var defaultPorts = map[string]string{
+	schemeHTTP:  "80",
+	schemeHTTPS: "443",
+}
+
+// TrailingSlash is the normalization policy for the
+// trailing slash on the paths of the links
+type TrailingSlash int
+
+const (
+	// TrailingSlashKeep leaves the paths as they are linked
+	TrailingSlashKeep TrailingSlash = iota
+
+	// TrailingSlashAdd adds a trailing slash to the paths,
+	// apart from paths to files, e.g. `/report.pdf`
+	TrailingSlashAdd
+
+	// TrailingSlashRemove removes the trailing slash from
+	// the paths, apart from the root path
+	TrailingSlashRemove
+)
+
+var trailingSlashNames = map[string]TrailingSlash{
+	"keep":   TrailingSlashKeep,
+	"add":    TrailingSlashAdd,
+	"remove": TrailingSlashRemove,
+}
+
+// ParseTrailingSlash parses the trailing slash policy
+// from its name, `keep`, `add` or `remove`
+func ParseTrailingSlash(name string) (TrailingSlash, error) {
+	policy, ok := trailingSlashNames[strings.ToLower(name)]
+	if !ok {
+		return TrailingSlashKeep, fmt.Errorf("unknown trailing slash policy %q", name)
+	}
+
+	return policy, nil
+}
+
+// apply applies the trailing slash policy to the url path
+func (t TrailingSlash) apply(u *url.URL) {
+	switch t {
+	case TrailingSlashAdd:
+		if u.Path == "" {
+			u.Path = "/"
+		}
+		if !strings.HasSuffix(u.Path, "/") && path.Ext(u.Path) == "" {
+			u.Path += "/"
+			if u.RawPath != "" {
+				u.RawPath += "/"
+			}
+		}
+	case TrailingSlashRemove:
+		if len(u.Path) > 1 && strings.HasSuffix(u.Path, "/") {
+			u.Path = strings.TrimRight(u.Path, "/")
+			u.RawPath = strings.TrimRight(u.RawPath, "/")
+			if u.Path == "" {
+				u.Path = "/"
+				u.RawPath = ""
+			}
+		}
+	}
+}
+
+// canonicalize passes back the canonical form of the url, this
+// is what the pages are keyed and deduped by. It drops the
+// fragment, lowercases the scheme and host, removes default
+// ports, cleans up the query, re-encodes the path consistently
+// and applies the trailing slash policy
+func (c Config) canonicalize(u url.URL) url.URL {
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+
+	host, port, err := net.SplitHostPort(u.Host)
+	if err == nil && defaultPorts[u.Scheme] == port {
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		u.Host = host
+	}
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	if c.LowercasePath {
+		u.Path = strings.ToLower(u.Path)
+		u.RawPath = strings.ToLower(u.RawPath)
+	}
+
+	// an encoded slash in the path isn't the same as a slash,
+	// so only then is the original encoding kept
+	if !strings.Contains(strings.ToUpper(u.RawPath), "%2F") {
+		u.RawPath = ""
+	}
+
+	c.TrailingSlash.apply(&u)
+	c.canonicalizeQuery(&u)
+
+	return u
+}
+
+// canonicalizeQuery strips or filters the query parameters
+// using the allow and deny lists, optionally sorts them by
+// key and re-encodes them consistently
+func (c Config) canonicalizeQuery(u *url.URL) {
+	u.ForceQuery = false
+	if c.StripQuery || u.RawQuery == "" {
+		u.RawQuery = ""
+		return
+	}
+
+	type param struct {
+		key      string
+		value    string
+		hasValue bool
+	}
+
+	var params []param
+	for _, part := range strings.Split(u.RawQuery, "&") {
+		if part == "" {
+			continue
+		}
+
+		var pr param
+		pr.key, pr.value = part, ""
+		if i := strings.Index(part, "="); i >= 0 {
+			pr.key, pr.value, pr.hasValue = part[:i], part[i+1:], true
+		}
+
+		if k, err := url.QueryUnescape(pr.key); err == nil {
+			pr.key = k
+		}
+		if v, err := url.QueryUnescape(pr.value); err == nil {
+			pr.value = v
+		}
+
+		if !c.queryParamAllowed(pr.key) {
+			continue
+		}
+
+		params = append(params, pr)
+	}
+
+	if c.SortQuery {
+		sort.SliceStable(params, func(i, j int) bool {
+			return params[i].key < params[j].key
+		})
+	}
+
+	var parts []string
+	for _, pr := range params {
+		part := url.QueryEscape(pr.key)
+		if pr.hasValue {
+			part += "=" + url.QueryEscape(pr.value)
+		}
+		parts = append(parts, part)
+	}
+
+	u.RawQuery = strings.Join(parts, "&")
+}
+
+// queryParamAllowed checks the query parameter key against
+// the allow and deny lists, which can use `path.Match`
+// patterns, e.g. `utm_*`
+func (c Config) queryParamAllowed(key string) bool {
+	key = strings.ToLower(key)
+	if len(c.QueryAllow) > 0 && !matchesAny(c.QueryAllow, key) {
+		return false
+	}
+
+	return !matchesAny(c.QueryDeny, key)
+}
+
+func matchesAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		ok, err := path.Match(strings.ToLower(pattern), key)
+		if err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
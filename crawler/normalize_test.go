@@ -0,0 +1,132 @@
+package crawler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func TestConfig_canonicalize_TrailingSlash(t *testing.T) {
+	tc := []struct {
+		Policy   TrailingSlash
+		URL      string
+		Expected string
+	}{
+		{Policy: TrailingSlashKeep, URL: "http://example.com/about", Expected: "http://example.com/about"},
+		{Policy: TrailingSlashKeep, URL: "http://example.com/about/", Expected: "http://example.com/about/"},
+		{Policy: TrailingSlashAdd, URL: "http://example.com/about", Expected: "http://example.com/about/"},
+		{Policy: TrailingSlashAdd, URL: "http://example.com/about/", Expected: "http://example.com/about/"},
+		{Policy: TrailingSlashAdd, URL: "http://example.com/report.pdf", Expected: "http://example.com/report.pdf"},
+		{Policy: TrailingSlashAdd, URL: "http://example.com/list?page=2", Expected: "http://example.com/list/?page=2"},
+		{Policy: TrailingSlashAdd, URL: "http://example.com", Expected: "http://example.com/"},
+		{Policy: TrailingSlashRemove, URL: "http://example.com/about/", Expected: "http://example.com/about"},
+		{Policy: TrailingSlashRemove, URL: "http://example.com/about", Expected: "http://example.com/about"},
+		{Policy: TrailingSlashRemove, URL: "http://example.com/", Expected: "http://example.com/"},
+		{Policy: TrailingSlashRemove, URL: "http://example.com/list/?page=2", Expected: "http://example.com/list?page=2"},
+		{Policy: TrailingSlashRemove, URL: "http://example.com/docs/api//", Expected: "http://example.com/docs/api"},
+	}
+
+	for _, v := range tc {
+		u, err := url.Parse(v.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := Config{TrailingSlash: v.Policy}
+		canonical := c.canonicalize(*u)
+		assert.Equal(t, v.Expected, canonical.String(), v.URL)
+	}
+}
+
+func TestParseTrailingSlash(t *testing.T) {
+	for name, expected := range map[string]TrailingSlash{
+		"keep":   TrailingSlashKeep,
+		"add":    TrailingSlashAdd,
+		"Remove": TrailingSlashRemove,
+	} {
+		policy, err := ParseTrailingSlash(name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, policy)
+	}
+
+	_, err := ParseTrailingSlash("sometimes")
+	assert.Error(t, err)
+}
+
+func TestConfig_canonicalize(t *testing.T) {
+	tc := []struct {
+		Name     string
+		Config   Config
+		URL      string
+		Expected string
+	}{
+		{Name: "fragment", URL: "http://example.com/a/#top", Expected: "http://example.com/a/"},
+		{Name: "scheme and host case", URL: "HTTP://Example.COM/Path/", Expected: "http://example.com/Path/"},
+		{Name: "default http port", URL: "http://example.com:80/a/", Expected: "http://example.com/a/"},
+		{Name: "default https port", URL: "https://example.com:443/a/", Expected: "https://example.com/a/"},
+		{Name: "other port", URL: "http://example.com:8080/a/", Expected: "http://example.com:8080/a/"},
+		{Name: "https on port 80", URL: "https://example.com:80/a/", Expected: "https://example.com:80/a/"},
+		{Name: "ipv6 default port", URL: "http://[::1]:80/a/", Expected: "http://[::1]/a/"},
+		{Name: "empty path", URL: "http://example.com", Expected: "http://example.com/"},
+		{Name: "decode unreserved", URL: "http://example.com/%7Euser/%41bc", Expected: "http://example.com/~user/Abc"},
+		{Name: "lowercase escapes", URL: "http://example.com/caf%c3%a9/", Expected: "http://example.com/caf%C3%A9/"},
+		{Name: "encoded slash kept", URL: "http://example.com/a%2Fb/", Expected: "http://example.com/a%2Fb/"},
+		{Name: "query order kept", URL: "http://example.com/?b=2&a=1", Expected: "http://example.com/?b=2&a=1"},
+		{Name: "query re-encoded", URL: "http://example.com/?q=a%20b&x=%7e", Expected: "http://example.com/?q=a+b&x=~"},
+		{Name: "empty query", URL: "http://example.com/?", Expected: "http://example.com/"},
+		{Name: "empty query params", URL: "http://example.com/?&&a=1&", Expected: "http://example.com/?a=1"},
+		{Name: "query key without value", URL: "http://example.com/?print&a=", Expected: "http://example.com/?print&a="},
+		{
+			Name:     "sort query",
+			Config:   Config{SortQuery: true},
+			URL:      "http://example.com/?b=2&a=1&c=3&a=0",
+			Expected: "http://example.com/?a=1&a=0&b=2&c=3",
+		},
+		{
+			Name:     "strip query",
+			Config:   Config{StripQuery: true},
+			URL:      "http://example.com/list?page=2",
+			Expected: "http://example.com/list",
+		},
+		{
+			Name:     "deny query",
+			Config:   Config{QueryDeny: []string{"utm_*", "sessionid"}},
+			URL:      "http://example.com/list?utm_source=a&page=2&SessionID=x&utm_medium=b",
+			Expected: "http://example.com/list?page=2",
+		},
+		{
+			Name:     "allow query",
+			Config:   Config{QueryAllow: []string{"page", "sort"}},
+			URL:      "http://example.com/list?page=2&ref=home&sort=asc",
+			Expected: "http://example.com/list?page=2&sort=asc",
+		},
+		{
+			Name:     "allow and deny query",
+			Config:   Config{QueryAllow: []string{"p*"}, QueryDeny: []string{"preview"}},
+			URL:      "http://example.com/?page=1&preview=1&q=x",
+			Expected: "http://example.com/?page=1",
+		},
+		{
+			Name:     "lowercase path",
+			Config:   Config{LowercasePath: true},
+			URL:      "http://example.com/Foo/Bar",
+			Expected: "http://example.com/foo/bar",
+		},
+		{
+			Name:     "lowercase path and trailing slash",
+			Config:   Config{LowercasePath: true, TrailingSlash: TrailingSlashAdd},
+			URL:      "http://example.com/Foo",
+			Expected: "http://example.com/foo/",
+		},
+	}
+
+	for _, v := range tc {
+		u, err := url.Parse(v.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		canonical := v.Config.canonicalize(*u)
+		assert.Equal(t, v.Expected, canonical.String(), v.Name)
+	}
+}
@@ -1,12 +1,13 @@
 package crawler
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"golang.org/x/net/html"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strings"
 	"time"
@@ -21,23 +22,97 @@ type Page struct {
 	IsRedirect  bool     `json:"is_redirect"`
 	RedirectsTo *url.URL `json:"redirects_to"`
 
-	crawler *Crawler `json:"-"`
-	err     error    `json:"-"`
+	// Depth is the click distance from the seed url, or
+	// the sitemap url, that the page was found at
+	Depth int `json:"depth"`
+
+	// Variants are the urls as they were linked that were
+	// merged into this page when canonicalized
+	Variants []string `json:"variants,omitempty"`
+
+	// Response is the status code, error, headers and
+	// timings recorded when fetching the page
+	Response Response `json:"response"`
+
+	// RedirectChain is every redirect followed when fetching
+	// the page, with any problems found in `RedirectWarnings`
+	RedirectChain    []RedirectHop     `json:"redirect_chain,omitempty"`
+	RedirectWarnings []RedirectWarning `json:"redirect_warnings,omitempty"`
+
+	// Attempts is the number of times the page was
+	// requested, more than 1 if it was retried
+	Attempts int `json:"attempts"`
+
+	// DiscoveredBy is how the page was found, by following
+	// links, from the sitemaps or both
+	DiscoveredBy Discovery `json:"discovered_by"`
+
+	// NoIndex and NoFollow are the flags from the meta robots
+	// tags and `X-Robots-Tag` headers of the page, including
+	// those aimed at the user agent
+	NoIndex  bool `json:"noindex"`
+	NoFollow bool `json:"nofollow"`
+
+	// Info is the on-page metadata of the page
+	Info PageInfo `json:"info"`
+
+	// Asset is set for the pages linked to by the link rules
+	// that aren't followed, e.g. images, which are requested
+	// to check them but aren't parsed
+	Asset bool `json:"asset"`
+
+	// SoftNotFound is set for the pages that were served
+	// successfully but look like a not found page, see
+	// `Config.DetectSoftNotFound`
+	SoftNotFound bool `json:"soft_not_found"`
+
+	// BlockedLinks are the links on the page that weren't
+	// crawled because the robots.txt disallows them
+	BlockedLinks []BlockedURL `json:"blocked_links,omitempty"`
+
+	crawler   *Crawler `json:"-"`
+	err       error    `json:"-"`
+	truncated bool     `json:"-"`
+
+	// retryAfter is how long the server asked
+	// to wait before the next request
+	retryAfter time.Duration
+
+	// robotsRule is the robots.txt rule that blocked
+	// the page, for the pages listed in the sitemaps
+	robotsRule string
+
+	// seed is set for the pages that are seed urls, which
+	// aren't orphans even if nothing links to them
+	seed bool
+
+	// linkVariants are the raw urls of the links on the
+	// page, keyed by their canonical url
+	linkVariants map[string][]string
+
+	// linkIndex is the index in `Links` of each link by its
+	// `seenKey`, for counting duplicate links
+	linkIndex map[string]int
 }
 
 // MarshalJSON generates the json and converts the `link`
-// and `LinkedFrom` from type `links` to `[]string` as well
-// as passing back the `Path` and `RedirectsTo` as `string`
+// and `LinkedFrom` from type `links` to `[]string` of their
+// canonical urls as well as passing back the `URL`, `Path`
+// and `RedirectsTo` as `string`, the out of scope, nofollow
+// and asset links are also listed on their own. The links
+// along with their metadata are passed back as `edges`
 func (p *Page) MarshalJSON() ([]byte, error) {
-	var links []string
-	var linkedFrom []string
-
+	var outOfScope, noFollow, assets []string
 	for _, l := range p.Links {
-		links = append(links, l.URL.Path)
-	}
-
-	for _, l := range p.LinkedFrom {
-		linkedFrom = append(linkedFrom, l.URL.Path)
+		if l.OutOfScope {
+			outOfScope = append(outOfScope, l.URL.String())
+		}
+		if l.NoFollow {
+			noFollow = append(noFollow, l.URL.String())
+		}
+		if l.Asset {
+			assets = append(assets, l.URL.String())
+		}
 	}
 
 	var redirectsTo *string
@@ -48,16 +123,26 @@ func (p *Page) MarshalJSON() ([]byte, error) {
 
 	type Alias Page
 	return json.Marshal(&struct {
+		URL         string   `json:"url"`
 		Path        string   `json:"path"`
 		RedirectsTo *string  `json:"redirects_to"`
 		Links       []string `json:"links"`
+		Edges       []Edge   `json:"edges"`
+		OutOfScope  []string `json:"out_of_scope_links,omitempty"`
+		NoFollow    []string `json:"nofollow_links,omitempty"`
+		Assets      []string `json:"asset_links,omitempty"`
 		LinkedFrom  []string `json:"linked_from"`
 		*Alias
 	}{
+		URL:         p.URL.String(),
 		Path:        p.URL.Path,
 		RedirectsTo: redirectsTo,
-		Links:       links,
-		LinkedFrom:  linkedFrom,
+		Links:       p.Links.URLs(),
+		Edges:       p.Links.Edges(),
+		OutOfScope:  outOfScope,
+		NoFollow:    noFollow,
+		Assets:      assets,
+		LinkedFrom:  p.LinkedFrom.URLs(),
 		Alias:       (*Alias)(p),
 	})
 }
@@ -72,39 +157,83 @@ func NewPage(url url.URL, crawler *Crawler) *Page {
 }
 
 // Run is what gets called when a worker starts work/crawling
-// on a page
-func (p Page) Run() {
-	p.crawl()
+// on a page, once the context is done the page stops
+// queueing links and doesn't pass back its result
+func (p Page) Run(ctx context.Context) {
+	p.crawl(ctx)
+
+	// checked once the page has released its slot with
+	// the rate limiter, as the host may need to be probed
+	if p.err == nil && p.crawler.config.DetectSoftNotFound && !p.Asset {
+		p.checkSoftNotFound(ctx)
+	}
 	if p.err == nil {
 		for _, link := range p.Links {
 			if !link.Crawled {
-				p.crawler.queue <- link.URL
+				select {
+				case p.crawler.queue <- link:
+				case <-ctx.Done():
+					return
+				}
 			}
 		}
 	}
 
-	p.crawler.results <- p
+	select {
+	case p.crawler.results <- p:
+	case <-ctx.Done():
+	}
 }
 
 // crawl is the main body of work for `Page`, it fetches
 // the body of the url and tries parsing the html and scanning
 // for links within the page
-func (p *Page) crawl() {
+func (p *Page) crawl(ctx context.Context) {
 	linkCache := make(map[string]bool)
+	p.Attempts++
 
-	resp, err := p.makeRequest(p.URL)
+	release, err := p.crawler.limiter.wait(ctx, &p.URL)
 	if err != nil {
-		p.err = err
+		p.setError(classifyError(err, ErrorRequest))
+		return
+	}
+	defer release()
+
+	// the time waiting for the rate limiter
+	// isn't part of the total time
+	start := time.Now()
+	defer func() {
+		p.Response.TotalTime = time.Since(start)
+	}()
+
+	resp, err := p.makeRequest(ctx, p.URL)
+	if err != nil {
+		p.setError(classifyError(err, ErrorRequest))
 		return
 	}
 	defer resp.Body.Close()
-	tokenizer := html.NewTokenizer(resp.Body)
+	body := &countingReader{Reader: resp.Body}
+	tokenizer := html.NewTokenizer(body)
 
-	if p.URL.Path != resp.Request.URL.Path ||
-		p.URL.Host != resp.Request.URL.Host {
+	if p.URL.String() != resp.Request.URL.String() {
 		p.IsRedirect = true
 		p.RedirectsTo = resp.Request.URL
 	}
+
+	if p.Asset {
+		return
+	}
+
+	// links are resolved against the url the page was
+	// actually served from, or the `<base href>` if set
+	base := resp.Request.URL
+	hasBase := false
+
+	// anchor is the index of the link whose anchor text is
+	// being read, -1 when outside of a stored link
+	anchor := -1
+	var regions regionStack
+	info := &infoParser{info: &p.Info, canonicalize: p.crawler.config.canonicalize}
 	for {
 		t := tokenizer.Next()
 		if t == html.ErrorToken {
@@ -113,90 +242,176 @@ func (p *Page) crawl() {
 				break
 			}
 
-			p.err = errors.New("error parsing html")
+			p.setError(classifyError(err, ErrorParse))
 			return
 		}
 		token := tokenizer.Token()
-		if token.DataAtom.String() == tokenAnchor {
+		info.token(t, token, base)
+		if token.DataAtom.String() == tokenBase && !hasBase {
 			for _, attr := range token.Attr {
 				if attr.Key == attrHref {
-					linkURL, err := p.getLinkURL(attr)
-					if err != nil {
-						continue
-					}
-					link, ok := p.parseLink(linkURL, p.URL, &linkCache)
-					if !ok {
-						continue
+					baseURL, err := base.Parse(strings.TrimSpace(attr.Val))
+					if err == nil {
+						base = baseURL
+						hasBase = true
 					}
-					p.Links = append(p.Links, link)
+				}
+			}
+		}
+
+		if token.DataAtom.String() == tokenMeta && t != html.EndTagToken {
+			var name, content string
+			for _, attr := range token.Attr {
+				switch attr.Key {
+				case attrName:
+					name = attr.Val
+				case attrContent:
+					content = attr.Val
+				}
+			}
+			p.applyMetaRobots(name, content)
+		}
+
+		if t == html.TextToken && anchor >= 0 {
+			p.Links[anchor].Text += token.Data
+		}
+
+		if token.DataAtom.String() == tokenAnchor && t == html.EndTagToken {
+			if anchor >= 0 {
+				p.Links[anchor].Text = strings.Join(strings.Fields(p.Links[anchor].Text), " ")
+			}
+			anchor = -1
+		}
+
+		if t == html.StartTagToken {
+			regions.push(token.Data)
+		}
+		if t == html.EndTagToken {
+			regions.pop(token.Data)
+		}
+
+		if token.DataAtom.String() == tokenImage && t != html.EndTagToken && anchor >= 0 {
+			p.Links[anchor].Image = true
+			for _, attr := range token.Attr {
+				if attr.Key == attrAlt {
+					p.Links[anchor].Text += " " + attr.Val
+				}
+			}
+		}
+
+		if t == html.StartTagToken || t == html.SelfClosingTagToken {
+			last := p.extractLinks(ctx, token, base, regions.current(), &linkCache)
+			if token.DataAtom.String() == tokenAnchor {
+				anchor = -1
+				if t == html.StartTagToken {
+					anchor = last
 				}
 			}
 		}
 	}
 
+	info.finish()
+	if p.Response.ContentLength < 0 {
+		p.Response.ContentLength = body.n
+	}
+
 	return
 }
 
 // makeRequest sets up the http client and does the
 // GETing of the page url, also checks for a successful
-// response
-func (p *Page) makeRequest(u url.URL) (*http.Response, error) {
-	request, err := http.NewRequest(http.MethodGet, u.String(), nil)
+// response, the request is aborted once the context is done.
+// The status code, headers, time to first byte and the
+// redirects followed are recorded on the page
+func (p *Page) makeRequest(ctx context.Context, u url.URL) (*http.Response, error) {
+	request, err := p.crawler.newRequest(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	client, err := p.crawler.httpClient()
 	if err != nil {
 		return nil, err
 	}
-	request.Header.Set(headerUserAgent, p.crawler.userAgent)
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+
+	start := time.Now()
+	trace := &httptrace.ClientTrace{
+		GotFirstResponseByte: func() {
+			p.Response.TimeToFirstByte = time.Since(start)
+		},
 	}
-	resp, err := client.Do(request)
+
+	redirects := &redirectRecorder{}
+	ctx = withRedirectRecorder(httptrace.WithClientTrace(ctx, trace), redirects)
+	resp, err := client.Do(request.WithContext(ctx))
+	p.RedirectChain = redirects.hops
+	p.RedirectWarnings = redirects.warnings(u, p.crawler.config.LongRedirectChain)
 	if err != nil {
 		return nil, err
 	}
-	ct := resp.Header.Get(headerContentType)
-	if resp.StatusCode >= http.StatusOK &&
-		resp.StatusCode < http.StatusBadRequest &&
-		strings.Contains(ct, contentTypeHTML) {
-		return resp, nil
+
+	headers := p.crawler.config.RecordHeaders
+	if headers == nil {
+		headers = DefaultRecordHeaders
+	}
+	p.Response.recordResponse(resp, headers)
+	p.applyRobotsTag(resp.Header)
+	p.retryAfter = parseRetryAfter(resp.Header.Get(headerRetryAfter), time.Now())
+
+	if err := checkResponse(resp); err != nil && !(p.Asset && err.Category == ErrorNonHTML) {
+		resp.Body.Close()
+		return nil, err
 	}
 
-	return nil, errors.New("invalid response")
+	return resp, nil
 }
 
-// getLinkURL parses and gets a new link from the href
-// on the anchor attribute
-func (p *Page) getLinkURL(attr html.Attribute) (*url.URL, error) {
-	v := strings.TrimLeft(attr.Val, "/")
-	if !strings.HasSuffix(v, "/") {
-		v = v + "/"
+// getLinkURL resolves the href against the base url following
+// RFC 3986 and drops the fragment. Links that aren't http(s),
+// e.g. `mailto:`, are passed back as errors
+func (p *Page) getLinkURL(base *url.URL, href string) (*url.URL, error) {
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return nil, err
 	}
 
-	linkURL, err := url.Parse(v)
-	if err != nil || !linkURL.IsAbs() {
-		if v == "/" {
-			v = ""
-		}
-		absLink := fmt.Sprintf("%s://%s/%s", p.crawler.url.Scheme, p.crawler.url.Host, v)
-		linkURL, err = url.Parse(absLink)
-		if err != nil {
-			return nil, err
-		}
+	linkURL := base.ResolveReference(ref)
+	if linkURL.Scheme != schemeHTTP && linkURL.Scheme != schemeHTTPS {
+		return nil, fmt.Errorf("unsupported link scheme %q", linkURL.Scheme)
+	}
+
+	linkURL.Fragment = ""
+	linkURL.RawFragment = ""
+	if linkURL.Path == "" {
+		linkURL.Path = "/"
 	}
 
-	return linkURL, err
+	return linkURL, nil
 }
 
-// parseLink checks to see if the link has already been queued
+// parseLink canonicalizes the link, checks to see if the link has already been queued
 // and validates the url to see if valid, checking for if
 // we can crawl it in context of the robots.txt and also
-// if the link is on the same host
-func (p *Page) parseLink(linkURL *url.URL, parent url.URL, cache *map[string]bool) (link, bool) {
+// if the link is on the same host. Assets are also checked on
+// other hosts, e.g. a CDN, without the scope rules applying
+// to them, as they aren't crawled. Links out of scope and
+// links past the max depth are kept but not queued, so they
+// can still be queued if found on a page closer to the seed url,
+// as are nofollow links unless they're being followed. Assets
+// are checked whatever their depth. Links blocked by the
+// robots.txt are stored in `BlockedLinks`
+func (p *Page) parseLink(ctx context.Context, linkURL *url.URL, parent url.URL, noFollow bool, asset bool, cache *map[string]bool) (link, bool) {
+	canonical := p.crawler.config.canonicalize(*linkURL)
 	link := link{
-		URL:     *linkURL,
+		URL:     canonical,
 		Crawled: true,
+		Depth:   p.Depth + 1,
 	}
+	raw := linkURL.String()
+	linkURL = &canonical
+	parent = p.crawler.config.canonicalize(parent)
 
-	if linkURL.Host != p.crawler.url.Host {
+	otherHost := linkURL.Host != p.crawler.url.Host
+	if otherHost && !asset {
 		return link, false
 	}
 
@@ -204,34 +419,124 @@ func (p *Page) parseLink(linkURL *url.URL, parent url.URL, cache *map[string]boo
 		return link, false
 	}
 
-	if !p.crawler.ignoreRobotsTxt &&
-		!p.crawler.robotsTxtParser.TestAgent(linkURL.Path, p.crawler.userAgent) {
+	key := linkURL.String()
+	if allowed, rule := p.crawler.robotsAllowed(ctx, linkURL); !allowed {
+		p.appendBlocked(BlockedURL{URL: key, Rule: rule})
 		return link, false
 	}
 
-	path := linkURL.Path
-	queued, ok := (*cache)[path]
-	if !ok || !queued {
-		queued, ok := p.crawler.queued.Load(path)
-		if !ok || !queued.(bool) {
-			link.Crawled = false
-			p.crawler.queued.Store(path, true)
+	if raw != key {
+		if p.linkVariants == nil {
+			p.linkVariants = make(map[string][]string)
+		}
+		p.linkVariants[key] = append(p.linkVariants[key], raw)
+	}
+
+	if !otherHost && !p.crawler.inScope(linkURL) {
+		link.OutOfScope = true
+		link.outcome = linkOutOfScope
+		return link, !p.seen(link, cache)
+	}
+
+	if noFollow && !p.crawler.config.FollowNoFollow {
+		link.NoFollow = true
+		link.outcome = linkNoFollow
+		return link, !p.seen(link, cache)
+	}
+
+	if !asset && p.crawler.config.MaxDepth > 0 && link.Depth > p.crawler.config.MaxDepth {
+		if _, ok := p.crawler.queued.Load(key); !ok {
+			p.truncated = true
 		}
 
-		(*cache)[path] = true
-	} else if queued {
+		link.outcome = linkTooDeep
+		return link, !p.seen(link, cache)
+	}
+
+	link.outcome = linkQueued
+	if p.seen(link, cache) {
 		return link, false
 	}
 
+	// the pages of a level are crawled at once, so only the
+	// first to store the url queues it
+	if _, loaded := p.crawler.queued.LoadOrStore(key, true); !loaded {
+		link.Crawled = false
+	}
+
 	return link, true
 }
 
+// linkOutcome is what became of a link when it was parsed,
+// a url linked to more than once on a page is only stored
+// again if it has a different outcome, e.g. a nofollow link
+// followed by a link that is queued
+type linkOutcome string
+
+const (
+	linkQueued     linkOutcome = "queued"
+	linkOutOfScope linkOutcome = "out_of_scope"
+	linkNoFollow   linkOutcome = "nofollow"
+	linkTooDeep    linkOutcome = "too_deep"
+)
+
+// seenKey is the key of the link in the links seen on the
+// page and in `linkIndex`, the url along with its outcome
+func (l link) seenKey() string {
+	return string(l.outcome) + " " + l.URL.String()
+}
+
+// seen marks the link as seen on the page and passes back
+// if it had already been seen with the same outcome
+func (p *Page) seen(l link, cache *map[string]bool) bool {
+	key := l.seenKey()
+	seen := (*cache)[key]
+	(*cache)[key] = true
+	return seen
+}
+
 func (p *Page) linkValid(linkURL *url.URL, parent url.URL) bool {
 	return !strings.Contains(linkURL.Path, emailProtectionString) &&
-		linkURL.Path != parent.Path &&
 		linkURL.String() != parent.String()
 }
 
+// setError stores the error on the page and
+// on its response
+func (p *Page) setError(err *PageError) {
+	p.err = err
+	p.Response.setError(err)
+}
+
+// appendBlocked stores the blocked link unless
+// it's already been stored
+func (p *Page) appendBlocked(blocked BlockedURL) {
+	for _, b := range p.BlockedLinks {
+		if b.URL == blocked.URL {
+			return
+		}
+	}
+
+	p.BlockedLinks = append(p.BlockedLinks, blocked)
+}
+
 func (p *Page) appendLinkedFrom(link link) {
 	p.LinkedFrom = append(p.LinkedFrom, link)
 }
+
+// appendVariants stores the raw urls that haven't
+// already been stored
+func (p *Page) appendVariants(raws []string) {
+	for _, raw := range raws {
+		exists := false
+		for _, v := range p.Variants {
+			if v == raw {
+				exists = true
+				break
+			}
+		}
+
+		if !exists {
+			p.Variants = append(p.Variants, raw)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package crawler
 
 import (
+	"context"
 	"github.com/m1/smap/test"
 	"github.com/stretchr/testify/assert"
+	"net/http"
 	"net/url"
 	"testing"
 )
@@ -32,7 +34,7 @@ func TestPage_Crawl(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	tp.crawl()
+	tp.crawl(context.Background())
 	assert.Equal(t, tc.Path, tp.URL.Path)
 	assert.Equal(t, tp.Links.Paths(), tc.Links)
 }
@@ -48,12 +50,14 @@ func TestPage_Crawl_BufErr(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	tp.crawl()
+	tp.crawl(context.Background())
 	if tp.err == nil {
 		t.Error("should be error")
 	}
 
-	assert.EqualError(t, tp.err, "invalid response")
+	assert.EqualError(t, tp.err, `non_html: non html content type ""`)
+	assert.Equal(t, ErrorNonHTML, tp.Response.ErrorCategory)
+	assert.Equal(t, http.StatusOK, tp.Response.StatusCode)
 }
 
 func TestPage_Crawl_NewReqErr(t *testing.T) {
@@ -69,7 +73,7 @@ func TestPage_Crawl_NewReqErr(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	tp.crawl()
+	tp.crawl(context.Background())
 	if tp.err == nil {
 		t.Error("should be error")
 	}
@@ -88,7 +92,7 @@ func TestPage_Crawl_ClientDoErr(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	tp.crawl()
+	tp.crawl(context.Background())
 	if tp.err == nil {
 		t.Error("should be error")
 	}
@@ -107,7 +111,7 @@ func TestPage_parseLink_NotSameHost(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	_, ok := tp.parseLink(childUrl, *s.Url, nil)
+	_, ok := tp.parseLink(context.Background(), childUrl, *s.Url, false, false, nil)
 	if ok {
 		t.Error("should error")
 		return
@@ -130,4 +134,103 @@ func TestPage_LinkValid_Err(t *testing.T) {
 		t.Error("should error")
 		return
 	}
-}
\ No newline at end of file
+}
+
+func TestPage_getLinkURL(t *testing.T) {
+	base, _ := url.Parse("http://example.com/docs/guide/index.html?v=1#intro")
+
+	tc := []struct {
+		Href     string
+		Expected string
+		Err      bool
+	}{
+		{Href: "/", Expected: "http://example.com/"},
+		{Href: "/1/", Expected: "http://example.com/1/"},
+		{Href: "page.html", Expected: "http://example.com/docs/guide/page.html"},
+		{Href: "./page.html", Expected: "http://example.com/docs/guide/page.html"},
+		{Href: "../api/", Expected: "http://example.com/docs/api/"},
+		{Href: "../../", Expected: "http://example.com/"},
+		{Href: "../../../../too-far", Expected: "http://example.com/too-far"},
+		{Href: "./", Expected: "http://example.com/docs/guide/"},
+		{Href: ".", Expected: "http://example.com/docs/guide/"},
+		{Href: "..", Expected: "http://example.com/docs/"},
+		{Href: "sub/dir/", Expected: "http://example.com/docs/guide/sub/dir/"},
+		{Href: "/a/./b/../c", Expected: "http://example.com/a/c"},
+		{Href: "/report.pdf", Expected: "http://example.com/report.pdf"},
+		{Href: "/files/archive.tar.gz", Expected: "http://example.com/files/archive.tar.gz"},
+		{Href: "//cdn.example.net/lib.js", Expected: "http://cdn.example.net/lib.js"},
+		{Href: "//example.com/other/", Expected: "http://example.com/other/"},
+		{Href: "https://example.com/secure/", Expected: "https://example.com/secure/"},
+		{Href: "HTTPS://example.com/upper/", Expected: "https://example.com/upper/"},
+		{Href: "http://other.com", Expected: "http://other.com/"},
+		{Href: "?page=2", Expected: "http://example.com/docs/guide/index.html?page=2"},
+		{Href: "/list?page=2&sort=asc", Expected: "http://example.com/list?page=2&sort=asc"},
+		{Href: "list/?q=a+b", Expected: "http://example.com/docs/guide/list/?q=a+b"},
+		{Href: "#section", Expected: "http://example.com/docs/guide/index.html?v=1"},
+		{Href: "/faq/#answer", Expected: "http://example.com/faq/"},
+		{Href: "", Expected: "http://example.com/docs/guide/index.html?v=1"},
+		{Href: "  /spaced/  ", Expected: "http://example.com/spaced/"},
+		{Href: "\n\t/tabbed/", Expected: "http://example.com/tabbed/"},
+		{Href: "/path;params", Expected: "http://example.com/path;params"},
+		{Href: "/caf%C3%A9/", Expected: "http://example.com/caf%C3%A9/"},
+		{Href: "/with%20space/", Expected: "http://example.com/with%20space/"},
+		{Href: "/a//b/", Expected: "http://example.com/a//b/"},
+		{Href: "mailto:someone@example.com", Err: true},
+		{Href: "tel:+441234567890", Err: true},
+		{Href: "javascript:void(0)", Err: true},
+		{Href: "data:text/html,hello", Err: true},
+		{Href: "ftp://example.com/file", Err: true},
+		{Href: "http://[::1", Err: true},
+		{Href: "%zz", Err: true},
+	}
+
+	tp, err := NewTestPage(base, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range tc {
+		linkURL, err := tp.getLinkURL(base, v.Href)
+		if v.Err {
+			assert.Error(t, err, v.Href)
+			continue
+		}
+
+		if assert.NoError(t, err, v.Href) {
+			assert.Equal(t, v.Expected, linkURL.String(), v.Href)
+		}
+	}
+}
+
+func TestPage_Crawl_BaseHref(t *testing.T) {
+	s := test.NewBaseHrefServer()
+	s.Start()
+	defer s.Close()
+
+	childUrl := *s.Url
+	childUrl.Path = "/docs/guide/"
+	tp, err := NewTestPage(s.Url, &childUrl)
+	if err != nil {
+		t.Error(err)
+	}
+	tp.crawl(context.Background())
+	assert.NoError(t, tp.err)
+	assert.Equal(t, []string{"/static/page.html", "/static/api/", "/absolute/"}, tp.Links.Paths())
+}
+
+func TestPage_Crawl_RelativeToRedirect(t *testing.T) {
+	s := test.NewBaseHrefServer()
+	s.Start()
+	defer s.Close()
+
+	childUrl := *s.Url
+	childUrl.Path = "/old/"
+	tp, err := NewTestPage(s.Url, &childUrl)
+	if err != nil {
+		t.Error(err)
+	}
+	tp.crawl(context.Background())
+	assert.NoError(t, tp.err)
+	assert.True(t, tp.IsRedirect)
+	assert.Equal(t, []string{"/new/sibling/", "/"}, tp.Links.Paths())
+}
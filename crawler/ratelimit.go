@@ -0,0 +1,159 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// rateLimiter paces the requests made to each host, using
+// a token bucket per host along with a limit on the number
+// of requests to a host in flight at once
+type rateLimiter struct {
+	// interval is the time it takes for a token to be added
+	// to the bucket from the requests per second, the least
+	// time between requests to each host, 0 means no limit
+	interval time.Duration
+
+	// crawlDelay passes back the robots.txt `Crawl-delay` of
+	// the host of the url, nil when the robots.txt is ignored
+	crawlDelay func(u *url.URL) time.Duration
+
+	// maxConns is the number of requests to a host that
+	// can be in flight at once, 0 means no limit
+	maxConns int
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimiter
+}
+
+// pause stops requests to the host of the url until the time,
+// for when the server asks for requests to be held off
+func (r *rateLimiter) pause(u *url.URL, until time.Time) {
+	if r == nil {
+		return
+	}
+
+	l := r.host(u)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until.After(l.next) {
+		l.next = until
+	}
+}
+
+// hostLimiter is the token bucket and connection slots
+// of a single host, the bucket holds a single token so
+// requests are spaced evenly rather than in bursts
+type hostLimiter struct {
+	mu sync.Mutex
+
+	// next is when the next token is added to the bucket
+	next time.Time
+
+	// interval is the time it takes for a token to be added
+	// to the bucket, the stricter of the requests per second
+	// and the `Crawl-delay` of the host, set once by `resolve`
+	interval time.Duration
+	resolve  sync.Once
+
+	// conns holds a slot for each request in flight
+	conns chan struct{}
+}
+
+// newRateLimiter passes back the rate limiter for the crawl,
+// the stricter of the requests per second and the robots.txt
+// `Crawl-delay` of each host for the user agent sets its pace
+func (c *Crawler) newRateLimiter() *rateLimiter {
+	limiter := &rateLimiter{
+		maxConns: c.config.MaxConnsPerHost,
+		hosts:    make(map[string]*hostLimiter),
+	}
+
+	if c.config.RequestsPerSecond > 0 {
+		limiter.interval = time.Duration(float64(time.Second) / c.config.RequestsPerSecond)
+	}
+
+	if !c.config.IgnoreRobotsTxt {
+		limiter.crawlDelay = func(u *url.URL) time.Duration {
+			return c.robotsFor(context.Background(), u).group(c.config.UserAgent).crawlDelay
+		}
+	}
+
+	return limiter
+}
+
+// host passes back the limiter of the host of the url,
+// creating it if it's the first request. The `Crawl-delay`
+// of the host is looked up the first time it's passed back
+func (r *rateLimiter) host(u *url.URL) *hostLimiter {
+	r.mu.Lock()
+	l, ok := r.hosts[u.Host]
+	if !ok {
+		l = &hostLimiter{interval: r.interval}
+		if r.maxConns > 0 {
+			l.conns = make(chan struct{}, r.maxConns)
+		}
+		r.hosts[u.Host] = l
+	}
+	r.mu.Unlock()
+
+	l.resolve.Do(func() {
+		if r.crawlDelay == nil {
+			return
+		}
+		if delay := r.crawlDelay(u); delay > l.interval {
+			l.interval = delay
+		}
+	})
+
+	return l
+}
+
+// wait blocks until a request can be made to the host of the
+// url, passing back the func that frees up its connection slot
+// once done with. An error is passed back if the context is
+// done first
+func (r *rateLimiter) wait(ctx context.Context, u *url.URL) (func(), error) {
+	if r == nil {
+		return func() {}, nil
+	}
+
+	l := r.host(u)
+	release := func() {}
+	if l.conns != nil {
+		select {
+		case l.conns <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		release = func() { <-l.conns }
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return release, nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return release, nil
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+}
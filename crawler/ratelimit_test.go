@@ -0,0 +1,168 @@
+package crawler
+
+import (
+	"context"
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+	"time"
+)
+
+var (
+	exampleURL = &url.URL{Scheme: "https", Host: "example.com"}
+	otherURL   = &url.URL{Scheme: "https", Host: "other.example.com"}
+)
+
+// assertSpacing checks the requests were spaced by the
+// interval on average, with some leeway for each gap as
+// the server sees them arrive with network jitter
+func assertSpacing(t *testing.T, times []time.Time, interval time.Duration) {
+	for i := 1; i < len(times); i++ {
+		gap := times[i].Sub(times[i-1])
+		assert.True(t, gap >= interval*3/4, "gap %s", gap)
+	}
+
+	avg := times[len(times)-1].Sub(times[0]) / time.Duration(len(times)-1)
+	assert.True(t, avg >= interval*95/100, "average %s", avg)
+}
+
+func TestCrawler_RunWithRequestsPerSecond(t *testing.T) {
+	log := &test.RequestLog{}
+	s := test.NewRateServer(log, 0)
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:        10,
+		IgnoreRobotsTxt:   true,
+		RequestsPerSecond: 20,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Len(t, crawler.SiteMap, 7)
+	assert.Len(t, log.Times(), 7)
+	assertSpacing(t, log.Times(), 50*time.Millisecond)
+
+	for _, page := range crawler.SiteMap {
+		assert.True(t, page.Response.TotalTime < 40*time.Millisecond, "%s took %s", page.URL.Path, page.Response.TotalTime)
+	}
+}
+
+func TestCrawler_RunWithCrawlDelay(t *testing.T) {
+	log := &test.RequestLog{}
+	s := test.NewRateServer(log, 0)
+	s.WithCrawlDelayRobots()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:        10,
+		UserAgent:         "test-robot",
+		RequestsPerSecond: 100,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Len(t, log.Times(), 7)
+	assertSpacing(t, log.Times(), 100*time.Millisecond)
+}
+
+func TestCrawler_RunWithCrawlDelay_Ignored(t *testing.T) {
+	log := &test.RequestLog{}
+	s := test.NewRateServer(log, 0)
+	s.WithCrawlDelayRobots()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:        10,
+		IgnoreRobotsTxt:   true,
+		RequestsPerSecond: 50,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, 20*time.Millisecond, crawler.limiter.host(s.Url).interval)
+}
+
+func TestCrawler_newRateLimiter_PerHost(t *testing.T) {
+	delayed := test.NewRateServer(&test.RequestLog{}, 0)
+	delayed.WithCrawlDelayRobots()
+	delayed.Start()
+	defer delayed.Close()
+
+	s := test.NewRateServer(&test.RequestLog{}, 0)
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		UserAgent:         "test-robot",
+		RequestsPerSecond: 50,
+	})
+	limiter := crawler.newRateLimiter()
+
+	assert.Equal(t, 20*time.Millisecond, limiter.host(s.Url).interval)
+	assert.Equal(t, 100*time.Millisecond, limiter.host(delayed.Url).interval)
+}
+
+func TestCrawler_RunWithMaxConnsPerHost(t *testing.T) {
+	log := &test.RequestLog{}
+	s := test.NewRateServer(log, 50*time.Millisecond)
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      10,
+		IgnoreRobotsTxt: true,
+		MaxConnsPerHost: 2,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Len(t, crawler.SiteMap, 7)
+	assert.Equal(t, 2, log.MaxActive())
+}
+
+func TestRateLimiter_Pause(t *testing.T) {
+	limiter := &rateLimiter{hosts: make(map[string]*hostLimiter)}
+
+	until := time.Now().Add(50 * time.Millisecond)
+	limiter.pause(exampleURL, until)
+	limiter.pause(exampleURL, time.Now())
+
+	release, err := limiter.wait(context.Background(), exampleURL)
+	assert.NoError(t, err)
+	release()
+	assert.False(t, time.Now().Before(until))
+	assert.True(t, limiter.host(otherURL).next.IsZero())
+}
+
+func TestRateLimiter_Wait_Cancelled(t *testing.T) {
+	limiter := &rateLimiter{
+		interval: time.Hour,
+		maxConns: 1,
+		hosts:    make(map[string]*hostLimiter),
+	}
+
+	release, err := limiter.wait(context.Background(), exampleURL)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = limiter.wait(ctx, exampleURL)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	release()
+	_, err = limiter.wait(context.Background(), otherURL)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,176 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+const (
+	// maxRedirects is the number of redirects followed
+	// before giving up on a page
+	maxRedirects = 10
+
+	// defaultLongRedirectChain is the number of hops a redirect
+	// chain can have before it's warned about
+	defaultLongRedirectChain = 3
+
+	headerLocation = "Location"
+)
+
+// RedirectWarning is a problem found with a redirect chain
+type RedirectWarning string
+
+// The problems that are warned about for redirect chains
+const (
+	RedirectLongChain      RedirectWarning = "long_chain"
+	RedirectLoop           RedirectWarning = "loop"
+	RedirectHTTPSDowngrade RedirectWarning = "https_downgrade"
+	RedirectLeavesHost     RedirectWarning = "leaves_host"
+)
+
+var (
+	errRedirectLoop     = errors.New("redirect loop")
+	errTooManyRedirects = fmt.Errorf("stopped after %d redirects", maxRedirects)
+)
+
+// RedirectHop is a single redirect response in a chain
+type RedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+
+	// to is the url the location resolved to
+	to *url.URL
+}
+
+// RedirectChain is the chain of redirects from a page
+// to the url it finally redirected to
+type RedirectChain struct {
+	From     string            `json:"from"`
+	To       string            `json:"to"`
+	Hops     []RedirectHop     `json:"hops"`
+	Warnings []RedirectWarning `json:"warnings,omitempty"`
+}
+
+type redirectsKey struct{}
+
+// redirectRecorder stores the hops of the redirects
+// followed for a single request
+type redirectRecorder struct {
+	hops []RedirectHop
+	loop bool
+}
+
+// withRedirectRecorder passes back a context that the
+// redirects followed by `checkRedirect` are recorded in
+func withRedirectRecorder(ctx context.Context, r *redirectRecorder) context.Context {
+	return context.WithValue(ctx, redirectsKey{}, r)
+}
+
+// checkRedirect is the `CheckRedirect` of the http client, it
+// records each hop in the recorder of the request context and
+// stops following redirect loops and overly long chains
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	r, ok := req.Context().Value(redirectsKey{}).(*redirectRecorder)
+	if !ok {
+		r = &redirectRecorder{}
+	}
+
+	if req.Response != nil {
+		r.hops = append(r.hops, RedirectHop{
+			URL:        via[len(via)-1].URL.String(),
+			StatusCode: req.Response.StatusCode,
+			Location:   req.Response.Header.Get(headerLocation),
+			to:         req.URL,
+		})
+	}
+
+	for _, v := range via {
+		if v.URL.String() == req.URL.String() {
+			r.loop = true
+			return errRedirectLoop
+		}
+	}
+
+	if len(via) >= maxRedirects {
+		return errTooManyRedirects
+	}
+
+	return nil
+}
+
+// warnings checks the recorded chain from the url
+// for the problems that are warned about
+func (r *redirectRecorder) warnings(from url.URL, longChain int) []RedirectWarning {
+	var warnings []RedirectWarning
+	if longChain <= 0 {
+		longChain = defaultLongRedirectChain
+	}
+
+	if len(r.hops) > longChain {
+		warnings = append(warnings, RedirectLongChain)
+	}
+
+	if r.loop {
+		warnings = append(warnings, RedirectLoop)
+	}
+
+	downgrade, leaves := false, false
+	for _, hop := range r.hops {
+		hopURL, err := url.Parse(hop.URL)
+		if err != nil || hop.to == nil {
+			continue
+		}
+
+		if hopURL.Scheme == schemeHTTPS && hop.to.Scheme == schemeHTTP {
+			downgrade = true
+		}
+
+		if hop.to.Host != from.Host {
+			leaves = true
+		}
+	}
+
+	if downgrade {
+		warnings = append(warnings, RedirectHTTPSDowngrade)
+	}
+
+	if leaves {
+		warnings = append(warnings, RedirectLeavesHost)
+	}
+
+	return warnings
+}
+
+// Redirects passes back the redirect chains of every
+// page that redirected, sorted by the url they're from
+func (s SiteMap) Redirects() []RedirectChain {
+	var chains []RedirectChain
+	for _, page := range s {
+		if len(page.RedirectChain) == 0 {
+			continue
+		}
+
+		to := ""
+		if page.RedirectsTo != nil {
+			to = page.RedirectsTo.String()
+		}
+
+		chains = append(chains, RedirectChain{
+			From:     page.URL.String(),
+			To:       to,
+			Hops:     page.RedirectChain,
+			Warnings: page.RedirectWarnings,
+		})
+	}
+
+	sort.Slice(chains, func(i, j int) bool {
+		return chains[i].From < chains[j].From
+	})
+
+	return chains
+}
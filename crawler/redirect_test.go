@@ -0,0 +1,75 @@
+package crawler
+
+import (
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestCrawler_Run_RedirectChains(t *testing.T) {
+	s := test.NewRedirectChainServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		KeepErrorPages:  true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	chain := crawler.SiteMap[s.URL+"/chain/"]
+	assert.True(t, chain.IsRedirect)
+	assert.Equal(t, "/final/", chain.RedirectsTo.Path)
+	assert.Equal(t, []RedirectHop{
+		{URL: s.URL + "/chain/", StatusCode: http.StatusMovedPermanently, Location: "/chain/1/"},
+		{URL: s.URL + "/chain/1/", StatusCode: http.StatusFound, Location: "/chain/2/"},
+		{URL: s.URL + "/chain/2/", StatusCode: http.StatusTemporaryRedirect, Location: "/chain/3/"},
+		{URL: s.URL + "/chain/3/", StatusCode: http.StatusPermanentRedirect, Location: "/final/"},
+	}, withoutTargets(chain.RedirectChain))
+	assert.Equal(t, []RedirectWarning{RedirectLongChain}, chain.RedirectWarnings)
+
+	loop := crawler.SiteMap[s.URL+"/loop/"]
+	assert.Len(t, loop.RedirectChain, 2)
+	assert.Equal(t, []RedirectWarning{RedirectLoop}, loop.RedirectWarnings)
+	assert.Equal(t, ErrorRedirect, loop.Response.ErrorCategory)
+
+	away := crawler.SiteMap[s.URL+"/away/"]
+	assert.Len(t, away.RedirectChain, 1)
+	assert.Equal(t, []RedirectWarning{RedirectLeavesHost}, away.RedirectWarnings)
+
+	var from []string
+	for _, c := range crawler.SiteMap.Redirects() {
+		from = append(from, c.From)
+	}
+	assert.Equal(t, []string{s.URL + "/away/", s.URL + "/chain/", s.URL + "/loop/"}, from)
+}
+
+func TestRedirectRecorder_warnings(t *testing.T) {
+	from, _ := url.Parse("https://example.com/a/")
+	to, _ := url.Parse("http://example.com/a/")
+
+	r := &redirectRecorder{
+		hops: []RedirectHop{
+			{URL: from.String(), StatusCode: http.StatusMovedPermanently, Location: to.String(), to: to},
+		},
+	}
+	assert.Equal(t, []RedirectWarning{RedirectHTTPSDowngrade}, r.warnings(*from, 0))
+
+	r.hops = append(r.hops, r.hops[0], r.hops[0])
+	assert.Equal(t, []RedirectWarning{RedirectLongChain, RedirectHTTPSDowngrade}, r.warnings(*from, 2))
+}
+
+func withoutTargets(hops []RedirectHop) []RedirectHop {
+	var stripped []RedirectHop
+	for _, hop := range hops {
+		hop.to = nil
+		stripped = append(stripped, hop)
+	}
+	return stripped
+}
@@ -0,0 +1,211 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrorCategory is the category of the error that
+// stopped a page from being crawled
+type ErrorCategory string
+
+// The categories of the errors that can stop a page from
+// being crawled, the status code ones are for responses
+// that were passed back but weren't successful
+const (
+	ErrorDNS         ErrorCategory = "dns"
+	ErrorConnect     ErrorCategory = "connect"
+	ErrorTLS         ErrorCategory = "tls"
+	ErrorTimeout     ErrorCategory = "timeout"
+	ErrorNonHTML     ErrorCategory = "non_html"
+	ErrorClient      ErrorCategory = "4xx"
+	ErrorRateLimited ErrorCategory = "rate_limited"
+	ErrorServer      ErrorCategory = "5xx"
+	ErrorParse       ErrorCategory = "parse"
+	ErrorRedirect    ErrorCategory = "redirect"
+	ErrorRequest     ErrorCategory = "request"
+	ErrorRobots      ErrorCategory = "robots"
+)
+
+// DefaultRecordHeaders are the response headers that are
+// recorded for each page if none are set in the config
+var DefaultRecordHeaders = []string{
+	"Content-Type",
+	"Last-Modified",
+	"Cache-Control",
+	"ETag",
+	"Server",
+	"X-Robots-Tag",
+}
+
+// PageError is the error for a page that couldn't be
+// crawled, along with the category of the error
+type PageError struct {
+	Category ErrorCategory
+	Err      error
+}
+
+// Error passes back the error message prefixed
+// with the category
+func (e *PageError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Category, e.Err.Error())
+}
+
+// Unwrap passes back the underlying error
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+// Response is what was recorded when fetching a page,
+// the status code, the error if there was one, the
+// headers of interest and the timings
+type Response struct {
+	StatusCode    int               `json:"status_code"`
+	ErrorCategory ErrorCategory     `json:"error_category,omitempty"`
+	Error         string            `json:"error,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	ContentLength int64             `json:"content_length"`
+
+	// TimeToFirstByte is the time from starting the request
+	// until the first byte of the response was read
+	TimeToFirstByte time.Duration `json:"-"`
+
+	// TotalTime is the time from starting the request
+	// until the body was read
+	TotalTime time.Duration `json:"-"`
+}
+
+// MarshalJSON generates the json with the timings
+// in milliseconds
+func (r Response) MarshalJSON() ([]byte, error) {
+	type Alias Response
+	return json.Marshal(&struct {
+		Alias
+		TimeToFirstByte float64 `json:"ttfb_ms"`
+		TotalTime       float64 `json:"total_time_ms"`
+	}{
+		Alias:           Alias(r),
+		TimeToFirstByte: milliseconds(r.TimeToFirstByte),
+		TotalTime:       milliseconds(r.TotalTime),
+	})
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// recordResponse stores the status code, content length
+// and the headers of interest from the response
+func (r *Response) recordResponse(resp *http.Response, headers []string) {
+	r.StatusCode = resp.StatusCode
+	r.ContentLength = resp.ContentLength
+
+	for _, h := range headers {
+		v := resp.Header.Get(h)
+		if v == "" {
+			continue
+		}
+
+		if r.Headers == nil {
+			r.Headers = make(map[string]string)
+		}
+		r.Headers[http.CanonicalHeaderKey(h)] = v
+	}
+}
+
+// setError stores the error along with its category
+func (r *Response) setError(err *PageError) {
+	r.ErrorCategory = err.Category
+	r.Error = err.Err.Error()
+}
+
+// checkResponse checks the response was successful and
+// html, passing back the categorized error if not
+func checkResponse(resp *http.Response) *PageError {
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return &PageError{Category: ErrorServer, Err: errors.New(resp.Status)}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return &PageError{Category: ErrorRateLimited, Err: errors.New(resp.Status)}
+	case resp.StatusCode >= http.StatusBadRequest:
+		return &PageError{Category: ErrorClient, Err: errors.New(resp.Status)}
+	case resp.StatusCode < http.StatusOK:
+		return &PageError{Category: ErrorRequest, Err: errors.New(resp.Status)}
+	}
+
+	ct := resp.Header.Get(headerContentType)
+	if !strings.Contains(ct, contentTypeHTML) {
+		return &PageError{
+			Category: ErrorNonHTML,
+			Err:      fmt.Errorf("non html content type %q", ct),
+		}
+	}
+
+	return nil
+}
+
+// classifyError categorizes the error from making the request
+// or reading the body, errors that aren't network errors fall
+// back to the category passed in
+func classifyError(err error, fallback ErrorCategory) *PageError {
+	var pageErr *PageError
+	if errors.As(err, &pageErr) {
+		return pageErr
+	}
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var opErr *net.OpError
+	var recordErr tls.RecordHeaderError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCertErr x509.CertificateInvalidError
+
+	category := fallback
+	switch {
+	case errors.Is(err, errRedirectLoop), errors.Is(err, errTooManyRedirects):
+		category = ErrorRedirect
+	case errors.Is(err, context.DeadlineExceeded):
+		category = ErrorTimeout
+	case errors.As(err, &dnsErr):
+		category = ErrorDNS
+	case errors.As(err, &certErr),
+		errors.As(err, &unknownAuthErr),
+		errors.As(err, &hostnameErr),
+		errors.As(err, &invalidCertErr),
+		errors.As(err, &recordErr),
+		strings.Contains(err.Error(), "tls: "):
+		category = ErrorTLS
+	case errors.As(err, &netErr) && netErr.Timeout():
+		category = ErrorTimeout
+	case errors.As(err, &opErr) && opErr.Op == "dial",
+		errors.Is(err, syscall.ECONNRESET):
+		category = ErrorConnect
+	}
+
+	return &PageError{Category: category, Err: err}
+}
+
+// countingReader counts the bytes read, for when
+// the response has no content length
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.Reader.Read(b)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,137 @@
+package crawler
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyError(t *testing.T) {
+	tc := []struct {
+		Err      error
+		Expected ErrorCategory
+	}{
+		{Err: &net.DNSError{Err: "no such host", Name: "example.invalid"}, Expected: ErrorDNS},
+		{Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, Expected: ErrorConnect},
+		{Err: &net.OpError{Op: "read", Err: timeoutError{}}, Expected: ErrorTimeout},
+		{Err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}, Expected: ErrorConnect},
+		{Err: context.DeadlineExceeded, Expected: ErrorTimeout},
+		{Err: x509.UnknownAuthorityError{}, Expected: ErrorTLS},
+		{Err: errors.New("remote error: tls: handshake failure"), Expected: ErrorTLS},
+		{Err: io.ErrUnexpectedEOF, Expected: ErrorParse},
+		{Err: &PageError{Category: ErrorClient, Err: errors.New("404 Not Found")}, Expected: ErrorClient},
+	}
+
+	for _, v := range tc {
+		assert.Equal(t, v.Expected, classifyError(v.Err, ErrorParse).Category, v.Err.Error())
+	}
+}
+
+func TestCrawler_Run_KeepErrorPages(t *testing.T) {
+	s := test.NewStatusServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		KeepErrorPages:  true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	index := crawler.SiteMap[s.URL+"/"]
+	assert.Equal(t, http.StatusOK, index.Response.StatusCode)
+	assert.Empty(t, index.Response.ErrorCategory)
+	assert.Equal(t, map[string]string{
+		"Content-Type":  "text/html; charset=utf-8",
+		"Last-Modified": "Wed, 13 Mar 2019 10:00:00 GMT",
+	}, index.Response.Headers)
+	assert.True(t, index.Response.ContentLength > 0)
+	assert.True(t, index.Response.TimeToFirstByte > 0)
+	assert.True(t, index.Response.TotalTime >= index.Response.TimeToFirstByte)
+	assert.ElementsMatch(t, []string{"/404/", "/500/", "/report.pdf", "/about/"}, index.Links.Paths())
+
+	tc := map[string]struct {
+		Status   int
+		Category ErrorCategory
+	}{
+		"/404/":       {Status: http.StatusNotFound, Category: ErrorClient},
+		"/500/":       {Status: http.StatusInternalServerError, Category: ErrorServer},
+		"/report.pdf": {Status: http.StatusOK, Category: ErrorNonHTML},
+	}
+
+	for path, v := range tc {
+		page, ok := crawler.SiteMap[s.URL+path]
+		if !ok {
+			t.Error("should exist")
+			continue
+		}
+
+		assert.Equal(t, v.Status, page.Response.StatusCode, path)
+		assert.Equal(t, v.Category, page.Response.ErrorCategory, path)
+		assert.NotEmpty(t, page.Response.Error, path)
+		assert.Contains(t, page.LinkedFrom.Paths(), "/")
+	}
+}
+
+func TestCrawler_Run_DropErrorPages(t *testing.T) {
+	s := test.NewStatusServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := New(*s.Url, true, 1, "")
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.ElementsMatch(t, []string{"/", "/about/"}, crawler.SiteMap.PathsCrawled())
+	assert.ElementsMatch(t, []string{"/about/"}, crawler.SiteMap[s.URL+"/"].Links.Paths())
+}
+
+func TestPage_Crawl_TLSErr(t *testing.T) {
+	s := test.NewServer()
+	s.StartTLS()
+	defer s.Close()
+
+	tp, err := NewTestPage(s.Url, s.Url)
+	if err != nil {
+		t.Error(err)
+	}
+	tp.crawl(context.Background())
+	if assert.Error(t, tp.err) {
+		assert.Equal(t, ErrorTLS, tp.Response.ErrorCategory)
+		assert.Equal(t, 0, tp.Response.StatusCode)
+	}
+}
+
+func TestPage_Crawl_ConnectErr(t *testing.T) {
+	s := test.NewServer()
+	s.Start()
+	s.Close()
+
+	tp, err := NewTestPage(s.Url, s.Url)
+	if err != nil {
+		t.Error(err)
+	}
+	tp.crawl(context.Background())
+	if assert.Error(t, tp.err) {
+		assert.Equal(t, ErrorConnect, tp.Response.ErrorCategory)
+	}
+}
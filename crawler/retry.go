@@ -0,0 +1,112 @@
+package crawler
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// DefaultRetryOn are the error categories that are
+// retried if none are set in the retry policy
+var DefaultRetryOn = []ErrorCategory{
+	ErrorTimeout,
+	ErrorConnect,
+	ErrorRateLimited,
+	ErrorServer,
+}
+
+// RetryPolicy is how pages that failed with a
+// transient error are retried
+type RetryPolicy struct {
+	// MaxAttempts is the most times a page is requested,
+	// 0 or 1 means pages aren't retried
+	MaxAttempts int
+
+	// BaseDelay is the backoff before the first retry, it's
+	// doubled for each retry after, defaults to 500ms
+	BaseDelay time.Duration
+
+	// MaxDelay caps the backoff, defaults to 30s
+	MaxDelay time.Duration
+
+	// RetryOn are the error categories that are
+	// retried, defaults to `DefaultRetryOn`
+	RetryOn []ErrorCategory
+}
+
+// retryable checks if the page failed with an error
+// that's retried and has attempts left
+func (r RetryPolicy) retryable(p *Page) bool {
+	if p.err == nil || p.Attempts >= r.MaxAttempts {
+		return false
+	}
+
+	retryOn := r.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = DefaultRetryOn
+	}
+
+	for _, category := range retryOn {
+		if p.Response.ErrorCategory == category {
+			return true
+		}
+	}
+
+	return false
+}
+
+// delay passes back the backoff before the retry following
+// the attempt, doubling the base delay for each attempt with
+// up to half of it taken off at random so that retries don't
+// line up. The `Retry-After` of the response is used instead
+// if it's longer
+func (r RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	base := r.BaseDelay
+	if base <= 0 {
+		base = defaultRetryBaseDelay
+	}
+
+	max := r.MaxDelay
+	if max <= 0 {
+		max = defaultRetryMaxDelay
+	}
+
+	backoff := base
+	for i := 1; i < attempt && backoff < max; i++ {
+		backoff *= 2
+	}
+
+	if backoff > max {
+		backoff = max
+	}
+
+	backoff -= time.Duration(rand.Int63n(int64(backoff)/2 + 1))
+	if retryAfter > backoff {
+		return retryAfter
+	}
+
+	return backoff
+}
+
+// retry requeues the page once its backoff has passed, the
+// page is passed back through the retries channel so that
+// no worker is held up while it waits
+func (c *Crawler) retry(ctx context.Context, p *Page) {
+	page := NewPage(p.URL, c)
+	page.Depth = p.Depth
+	page.Attempts = p.Attempts
+	page.Asset = p.Asset
+
+	delay := c.config.Retry.delay(p.Attempts, p.retryAfter)
+	time.AfterFunc(delay, func() {
+		select {
+		case c.retries <- page:
+		case <-ctx.Done():
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package crawler
+
+import (
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for i := 0; i < 20; i++ {
+		delay := policy.delay(1, 0)
+		assert.True(t, delay >= 50*time.Millisecond && delay <= 100*time.Millisecond, delay)
+
+		delay = policy.delay(3, 0)
+		assert.True(t, delay >= 200*time.Millisecond && delay <= 400*time.Millisecond, delay)
+
+		delay = policy.delay(10, 0)
+		assert.True(t, delay >= 500*time.Millisecond && delay <= time.Second, delay)
+	}
+
+	assert.Equal(t, 5*time.Second, policy.delay(1, 5*time.Second))
+}
+
+func TestRetryPolicy_Retryable(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 2}
+
+	page := &Page{Attempts: 1}
+	assert.False(t, policy.retryable(page))
+
+	page.setError(&PageError{Category: ErrorServer, Err: assert.AnError})
+	assert.True(t, policy.retryable(page))
+
+	page.Attempts = 2
+	assert.False(t, policy.retryable(page))
+
+	page = &Page{Attempts: 1}
+	page.setError(&PageError{Category: ErrorClient, Err: assert.AnError})
+	assert.False(t, policy.retryable(page))
+
+	policy.RetryOn = []ErrorCategory{ErrorClient}
+	assert.True(t, policy.retryable(page))
+}
+
+func TestCrawler_RunWithRetries(t *testing.T) {
+	log := &test.RequestLog{}
+	s := test.NewRetryServer(log)
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      2,
+		IgnoreRobotsTxt: true,
+		KeepErrorPages:  true,
+		Retry: RetryPolicy{
+			MaxAttempts: 3,
+			BaseDelay:   10 * time.Millisecond,
+		},
+	})
+
+	start := time.Now()
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, 1, crawler.SiteMap[s.URL+"/"].Attempts)
+
+	flaky := crawler.SiteMap[s.URL+"/flaky/"]
+	assert.Equal(t, 3, flaky.Attempts)
+	assert.Empty(t, flaky.Response.ErrorCategory)
+
+	down := crawler.SiteMap[s.URL+"/down/"]
+	assert.Equal(t, 3, down.Attempts)
+	assert.Equal(t, ErrorServer, down.Response.ErrorCategory)
+
+	missing := crawler.SiteMap[s.URL+"/missing/"]
+	assert.Equal(t, 1, missing.Attempts)
+	assert.Equal(t, ErrorClient, missing.Response.ErrorCategory)
+
+	limited := crawler.SiteMap[s.URL+"/limited/"]
+	assert.Equal(t, 2, limited.Attempts)
+	assert.Empty(t, limited.Response.ErrorCategory)
+	assert.True(t, time.Since(start) >= 900*time.Millisecond, "retry after not honored")
+
+	assert.Len(t, log.Paths(), 11)
+}
+
+func TestCrawler_RunWithRetries_NotBlocking(t *testing.T) {
+	log := &test.RequestLog{}
+	s := test.NewRetryServer(log)
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		Retry: RetryPolicy{
+			MaxAttempts: 2,
+			BaseDelay:   400 * time.Millisecond,
+			RetryOn:     []ErrorCategory{ErrorServer},
+		},
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	// the single worker carries on with the other pages
+	// while the failed ones wait to be retried
+	paths := log.Paths()
+	assert.ElementsMatch(t, []string{"/", "/flaky/", "/down/", "/missing/", "/limited/", "/ok/"}, paths[:6])
+	assert.ElementsMatch(t, []string{"/flaky/", "/down/"}, paths[6:])
+}
+
+func TestCrawler_RunWithoutRetries(t *testing.T) {
+	log := &test.RequestLog{}
+	s := test.NewRetryServer(log)
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		KeepErrorPages:  true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	flaky := crawler.SiteMap[s.URL+"/flaky/"]
+	assert.Equal(t, 1, flaky.Attempts)
+	assert.Equal(t, ErrorServer, flaky.Response.ErrorCategory)
+	assert.Equal(t, ErrorRateLimited, crawler.SiteMap[s.URL+"/limited/"].Response.ErrorCategory)
+	assert.Len(t, log.Paths(), 6)
+}
+
+func TestCrawler_RunWithRetries_Asset(t *testing.T) {
+	s := test.NewRetryAssetServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		KeepErrorPages:  true,
+		CheckAssets:     true,
+		Retry: RetryPolicy{
+			MaxAttempts: 2,
+			BaseDelay:   10 * time.Millisecond,
+		},
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	image, ok := crawler.SiteMap[s.URL+"/flaky.png"]
+	if !ok {
+		t.Fatal("should exist")
+	}
+	assert.Equal(t, 2, image.Attempts)
+	assert.True(t, image.Asset)
+	assert.Empty(t, image.Response.ErrorCategory)
+	assert.Empty(t, crawler.SiteMap.BrokenLinks())
+}
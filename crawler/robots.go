@@ -0,0 +1,415 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// robotsMaxBytes is how much of a robots.txt is parsed,
+	// RFC 9309 asks for at least 500 KiB to be parsed
+	robotsMaxBytes = 500 * 1024
+
+	// robotsMaxRedirects is how many redirects are followed
+	// when fetching a robots.txt
+	robotsMaxRedirects = 5
+
+	robotsPath = "/robots.txt"
+)
+
+var errRobotsRedirects = errors.New("too many robots.txt redirects")
+
+// BlockedURL is a url that wasn't crawled because the
+// robots.txt disallows it, along with the rule that matched
+type BlockedURL struct {
+	URL  string `json:"url"`
+	Rule string `json:"rule"`
+}
+
+// robots is the parsed robots.txt of a host. When the
+// robots.txt couldn't be fetched `unreachable` holds
+// why and everything is disallowed
+type robots struct {
+	groups   []robotsGroup
+	sitemaps []string
+
+	unreachable string
+}
+
+// robotsGroup is a group of rules and the user agents
+// they're for
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// robotsRule is a single allow or disallow line,
+// `pattern` being the path matcher of the line
+type robotsRule struct {
+	allow   bool
+	path    string
+	pattern *regexp.Regexp
+}
+
+// String passes back the rule as it's written
+// in the robots.txt
+func (r robotsRule) String() string {
+	if r.allow {
+		return "Allow: " + r.path
+	}
+	return "Disallow: " + r.path
+}
+
+// robotsEntry is a host in the robots.txt cache, fetched
+// once by the first url on the host to be tested
+type robotsEntry struct {
+	once   sync.Once
+	robots *robots
+}
+
+// robotsFor passes back the robots.txt for the scheme and
+// host of the url, fetching it if it's not been fetched yet
+func (c *Crawler) robotsFor(ctx context.Context, u *url.URL) *robots {
+	key := u.Scheme + "://" + u.Host
+
+	c.robotsMu.Lock()
+	if c.robots == nil {
+		c.robots = make(map[string]*robotsEntry)
+	}
+	entry, ok := c.robots[key]
+	if !ok {
+		entry = &robotsEntry{}
+		c.robots[key] = entry
+	}
+	c.robotsMu.Unlock()
+
+	entry.once.Do(func() {
+		entry.robots = c.fetchRobots(ctx, key+robotsPath)
+	})
+
+	return entry.robots
+}
+
+// robotsAllowed checks the url against the robots.txt of its
+// host, passing back the rule that blocked it if it's disallowed
+func (c *Crawler) robotsAllowed(ctx context.Context, u *url.URL) (bool, string) {
+	if c.config.IgnoreRobotsTxt {
+		return true, ""
+	}
+
+	return c.robotsFor(ctx, u).test(c.config.UserAgent, u)
+}
+
+// keepBlocked keeps the url blocked by the robots.txt as an
+// error page, if the error pages are being kept and it's not
+// already in the sitemap, passing back if it was kept
+func (c *Crawler) keepBlocked(u url.URL, rule string) bool {
+	key := u.String()
+	if _, ok := c.SiteMap[key]; ok || !c.config.KeepErrorPages {
+		return false
+	}
+
+	page := NewPage(u, c)
+	page.robotsRule = rule
+	page.setError(&PageError{
+		Category: ErrorRobots,
+		Err:      fmt.Errorf("%w: %s", errBlockedByRobots, rule),
+	})
+	c.SiteMap[key] = page
+	return true
+}
+
+// fetchRobots fetches and parses the robots.txt following RFC 9309,
+// redirects are followed up to 5 times and only the first 500 KiB
+// is parsed. A 4xx, or too many redirects, means there are no rules,
+// whereas a 5xx or network error means everything is disallowed
+func (c *Crawler) fetchRobots(ctx context.Context, robotsURL string) *robots {
+	request, err := c.newRequest(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return &robots{unreachable: err.Error()}
+	}
+
+	client, err := c.httpClient()
+	if err != nil {
+		return &robots{unreachable: err.Error()}
+	}
+
+	robotsClient := *client
+	robotsClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > robotsMaxRedirects {
+			return errRobotsRedirects
+		}
+		return nil
+	}
+
+	resp, err := robotsClient.Do(request)
+	if err != nil {
+		if errors.Is(err, errRobotsRedirects) {
+			return &robots{}
+		}
+		return &robots{unreachable: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return &robots{unreachable: resp.Status}
+	case resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices:
+		return &robots{}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, robotsMaxBytes))
+	if err != nil {
+		return &robots{unreachable: err.Error()}
+	}
+
+	return parseRobots(body)
+}
+
+// parseRobots parses the robots.txt, lines that can't be
+// parsed are skipped. Consecutive user agent lines start a
+// group, and rules outside of a group are ignored
+func parseRobots(body []byte) *robots {
+	r := &robots{}
+	var group *robotsGroup
+	inAgents := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), robotsMaxBytes+1)
+	scanner.Split(scanRobotsLines)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				r.groups = append(r.groups, robotsGroup{})
+				group = &r.groups[len(r.groups)-1]
+			}
+			group.agents = append(group.agents, strings.ToLower(value))
+			inAgents = true
+			continue
+		case "sitemap":
+			if value != "" {
+				r.sitemaps = append(r.sitemaps, value)
+			}
+			continue
+		}
+
+		inAgents = false
+		if group == nil {
+			continue
+		}
+
+		switch key {
+		case "allow", "disallow":
+			if value == "" {
+				continue
+			}
+			pattern, err := robotsPattern(value)
+			if err != nil {
+				continue
+			}
+			group.rules = append(group.rules, robotsRule{
+				allow:   key == "allow",
+				path:    value,
+				pattern: pattern,
+			})
+		case "crawl-delay":
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err == nil && seconds > 0 {
+				group.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	return r
+}
+
+// scanRobotsLines splits the robots.txt into lines, which
+// can end with any of `\n`, `\r\n` or `\r`
+func scanRobotsLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\r' {
+			if i+1 < len(data) && data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			if i+1 == len(data) && !atEOF {
+				return 0, nil, nil
+			}
+		}
+		return i + 1, data[:i], nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
+
+// robotsPattern compiles the path of the rule, `*` matches
+// any characters and a trailing `$` anchors the end
+func robotsPattern(path string) (*regexp.Regexp, error) {
+	end := strings.HasSuffix(path, "$")
+	path = strings.TrimSuffix(path, "$")
+
+	parts := strings.Split(path, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+
+	expr := "^" + strings.Join(parts, ".*")
+	if end {
+		expr += "$"
+	}
+
+	return regexp.Compile(expr)
+}
+
+// group passes back the rules for the user agent, merging
+// the groups that match it. The groups for the product token
+// of the user agent are used, or the `*` groups if none match,
+// the rules are sorted longest first
+func (r *robots) group(userAgent string) *robotsGroup {
+	var matched, wildcard []robotsGroup
+	for _, g := range r.groups {
+		match, all := false, false
+		for _, agent := range g.agents {
+			match = match || agentMatches(userAgent, agent)
+			all = all || agent == "*"
+		}
+
+		switch {
+		case match:
+			matched = append(matched, g)
+		case all:
+			wildcard = append(wildcard, g)
+		}
+	}
+
+	if len(matched) == 0 {
+		matched = wildcard
+	}
+
+	merged := &robotsGroup{}
+	for _, g := range matched {
+		merged.rules = append(merged.rules, g.rules...)
+		if merged.crawlDelay == 0 {
+			merged.crawlDelay = g.crawlDelay
+		}
+	}
+
+	sort.SliceStable(merged.rules, func(i, j int) bool {
+		a, b := merged.rules[i], merged.rules[j]
+		if len(a.path) != len(b.path) {
+			return len(a.path) > len(b.path)
+		}
+		return a.allow && !b.allow
+	})
+
+	return merged
+}
+
+// agentMatches checks if the user agent token of a robots
+// rule is the product token of the user agent, case
+// insensitively as in RFC 9309
+func agentMatches(userAgent, agent string) bool {
+	return agent != "" && strings.EqualFold(productToken(userAgent), agent)
+}
+
+// productToken passes back the name of the crawler from
+// the user agent, the part before the version or comments,
+// e.g. `smap` for `smap/1.0 (+https://example.com)`
+func productToken(userAgent string) string {
+	userAgent = strings.TrimSpace(userAgent)
+	if i := strings.IndexAny(userAgent, "/ \t"); i >= 0 {
+		return userAgent[:i]
+	}
+	return userAgent
+}
+
+// test checks if the user agent is allowed to crawl the url,
+// passing back the rule that disallowed it if not. The longest
+// matching rule wins, with allow winning a tie
+func (r *robots) test(userAgent string, u *url.URL) (bool, string) {
+	if r.unreachable != "" {
+		return false, fmt.Sprintf("robots.txt unreachable (%s)", r.unreachable)
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if path == robotsPath {
+		return true, ""
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	for _, rule := range r.group(userAgent).rules {
+		if rule.pattern.MatchString(path) {
+			if rule.allow {
+				return true, ""
+			}
+			return false, rule.String()
+		}
+	}
+
+	return true, ""
+}
+
+// Blocked passes back the urls that weren't crawled because
+// the robots.txt disallows them, along with the rule that
+// blocked each, sorted by url
+func (s SiteMap) Blocked() []BlockedURL {
+	rules := make(map[string]string)
+	for _, page := range s {
+		for _, b := range page.BlockedLinks {
+			rules[b.URL] = b.Rule
+		}
+
+		if page.robotsRule != "" {
+			rules[page.URL.String()] = page.robotsRule
+		}
+	}
+
+	var blocked []BlockedURL
+	for u, rule := range rules {
+		blocked = append(blocked, BlockedURL{URL: u, Rule: rule})
+	}
+
+	sort.Slice(blocked, func(i, j int) bool {
+		return blocked[i].URL < blocked[j].URL
+	})
+
+	return blocked
+}
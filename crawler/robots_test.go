@@ -0,0 +1,172 @@
+package crawler
+
+import (
+	"context"
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRobots_test(t *testing.T) {
+	r := parseRobots([]byte("# comment\r" +
+		"User-agent: *\n" +
+		"Disallow: /private/ # trailing comment\n" +
+		"Allow: /private/public/\n" +
+		"Disallow: /*.pdf$\n" +
+		"Disallow: /search?\n" +
+		"Allow: /tie\n" +
+		"Disallow: /tie\n" +
+		"\n" +
+		"User-agent: other-bot\n" +
+		"User-agent: test-robot\n" +
+		"Disallow: /robot/\n" +
+		"Crawl-delay: 1.5\n" +
+		"Sitemap: https://example.com/sitemap.xml\n" +
+		"Disallow:\n" +
+		"\n" +
+		"User-agent: robot\n" +
+		"User-agent: t\n" +
+		"Disallow: /substring/\n"))
+
+	tcs := []struct {
+		agent string
+		path  string
+		rule  string
+	}{
+		{"smap", "/", ""},
+		{"smap", "/private/page", "Disallow: /private/"},
+		{"smap", "/private/public/page", ""},
+		{"smap", "/file.pdf", "Disallow: /*.pdf$"},
+		{"smap", "/file.pdf?download=1", ""},
+		{"smap", "/search?q=smap", "Disallow: /search?"},
+		{"smap", "/tie", ""},
+		{"smap", "/robots.txt", ""},
+		{"smap", "/robot/", ""},
+		{"Test-Robot/1.0", "/robot/page", "Disallow: /robot/"},
+		{"Test-Robot/1.0", "/private/", ""},
+		{"Test-Robot/1.0", "/substring/", ""},
+		{"test-robot (+https://example.com)", "/robot/", "Disallow: /robot/"},
+		{"smap", "/substring/", ""},
+		{"Robot/2.0", "/substring/", "Disallow: /substring/"},
+		{"Robot/2.0", "/private/", ""},
+	}
+
+	for _, tc := range tcs {
+		u, err := url.Parse("https://example.com" + tc.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		allowed, rule := r.test(tc.agent, u)
+		assert.Equal(t, tc.rule == "", allowed, tc.path)
+		assert.Equal(t, tc.rule, rule, tc.path)
+	}
+
+	assert.Equal(t, []string{"https://example.com/sitemap.xml"}, r.sitemaps)
+	assert.Equal(t, 1500*time.Millisecond, r.group("test-robot").crawlDelay)
+	assert.Zero(t, r.group("smap").crawlDelay)
+}
+
+func TestCrawler_robotsFor_Status(t *testing.T) {
+	tcs := []struct {
+		status  int
+		allowed bool
+		rule    string
+	}{
+		{http.StatusOK, false, "Disallow: /"},
+		{http.StatusBadRequest, true, ""},
+		{http.StatusNotFound, true, ""},
+		{http.StatusTooManyRequests, true, ""},
+		{http.StatusInternalServerError, false, "robots.txt unreachable (500 Internal Server Error)"},
+		{http.StatusServiceUnavailable, false, "robots.txt unreachable (503 Service Unavailable)"},
+	}
+
+	for _, tc := range tcs {
+		s := test.NewServer()
+		s.WithRobotsStatus(tc.status)
+		s.Start()
+
+		crawler := New(*s.Url, false, 1, "test-robot")
+		allowed, rule := crawler.robotsAllowed(context.Background(), s.Url)
+		assert.Equal(t, tc.allowed, allowed, tc.status)
+		assert.Equal(t, tc.rule, rule, tc.status)
+
+		s.Close()
+	}
+}
+
+func TestCrawler_robotsFor_Redirects(t *testing.T) {
+	tcs := []struct {
+		redirects int
+		allowed   bool
+	}{
+		{1, false},
+		{5, false},
+		{6, true},
+	}
+
+	for _, tc := range tcs {
+		s := test.NewServer()
+		s.WithRedirectedRobots(tc.redirects)
+		s.Start()
+
+		crawler := New(*s.Url, false, 1, "test-robot")
+		allowed, _ := crawler.robotsAllowed(context.Background(), &url.URL{
+			Scheme: s.Url.Scheme,
+			Host:   s.Url.Host,
+			Path:   "/2/",
+		})
+		assert.Equal(t, tc.allowed, allowed, tc.redirects)
+
+		s.Close()
+	}
+}
+
+func TestCrawler_robotsFor_MaxBytes(t *testing.T) {
+	s := test.NewServer()
+	s.WithLargeRobots()
+	s.Start()
+	defer s.Close()
+
+	crawler := New(*s.Url, false, 1, "test-robot")
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Contains(t, crawler.SiteMap, s.URL+"/1/")
+	assert.Equal(t, []BlockedURL{
+		{URL: s.URL + "/2/", Rule: "Disallow: /2/"},
+	}, crawler.SiteMap.Blocked())
+}
+
+func TestCrawler_robotsFor_PerHost(t *testing.T) {
+	okay := test.NewServer()
+	okay.WithOkayRobots()
+	okay.Start()
+	defer okay.Close()
+
+	down := test.NewServer()
+	down.WithRobotsStatus(http.StatusServiceUnavailable)
+	down.Start()
+	defer down.Close()
+
+	crawler := New(*okay.Url, false, 1, "test-robot")
+	ctx := context.Background()
+
+	allowed, _ := crawler.robotsAllowed(ctx, &url.URL{Scheme: "http", Host: okay.Url.Host, Path: "/1/"})
+	assert.True(t, allowed)
+
+	allowed, rule := crawler.robotsAllowed(ctx, &url.URL{Scheme: "http", Host: down.Url.Host, Path: "/1/"})
+	assert.False(t, allowed)
+	assert.Equal(t, "robots.txt unreachable (503 Service Unavailable)", rule)
+
+	allowed, _ = crawler.robotsAllowed(ctx, &url.URL{Scheme: "https", Host: okay.Url.Host, Path: "/1/"})
+	assert.False(t, allowed)
+
+	assert.Len(t, crawler.robots, 3)
+	assert.Same(t, crawler.robotsFor(ctx, okay.Url), crawler.robotsFor(ctx, okay.Url))
+}
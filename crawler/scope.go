@@ -0,0 +1,131 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	scopePrefix       = "prefix:"
+	scopeGlobPrefix   = "glob:"
+	scopeRegexpPrefix = "regex:"
+)
+
+// ScopeRule is a rule that is matched against the links
+// before they are queued, to decide if they are in the
+// scope of the crawl
+type ScopeRule interface {
+	Match(u *url.URL) bool
+}
+
+// PathPrefix matches the links whose path starts with
+// the prefix, e.g. `/docs/`. A prefix without a trailing
+// slash only matches whole segments, so `/docs` matches
+// `/docs` and `/docs/guide/` but not `/docs-old/`
+type PathPrefix string
+
+// Match checks if the link path starts with the prefix
+func (p PathPrefix) Match(u *url.URL) bool {
+	prefix := string(p)
+	if strings.HasSuffix(prefix, "/") {
+		return strings.HasPrefix(u.Path, prefix)
+	}
+	return u.Path == prefix || strings.HasPrefix(u.Path, prefix+"/")
+}
+
+// PathGlob matches the links whose path matches the glob
+// pattern using the `path.Match` syntax, e.g. `/*/cart/`
+type PathGlob string
+
+// Match checks if the link path matches the glob pattern
+func (p PathGlob) Match(u *url.URL) bool {
+	ok, err := path.Match(string(p), u.Path)
+	return err == nil && ok
+}
+
+// PathRegexp matches the links whose path matches
+// the regular expression
+type PathRegexp struct {
+	*regexp.Regexp
+}
+
+// Match checks if the link path matches the regular expression
+func (p PathRegexp) Match(u *url.URL) bool {
+	return p.MatchString(u.Path)
+}
+
+// ParseScopeRule parses a rule from a string, `glob:` and `regex:`
+// prefixes make glob and regular expression rules, anything else
+// is a path prefix with an optional `prefix:`
+func ParseScopeRule(rule string) (ScopeRule, error) {
+	switch {
+	case strings.HasPrefix(rule, scopeGlobPrefix):
+		glob := strings.TrimPrefix(rule, scopeGlobPrefix)
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %s", glob, err)
+		}
+		return PathGlob(glob), nil
+	case strings.HasPrefix(rule, scopeRegexpPrefix):
+		re, err := regexp.Compile(strings.TrimPrefix(rule, scopeRegexpPrefix))
+		if err != nil {
+			return nil, err
+		}
+		return PathRegexp{re}, nil
+	}
+
+	return PathPrefix(strings.TrimPrefix(rule, scopePrefix)), nil
+}
+
+// inScope checks the link against the include and exclude
+// rules, a link is in scope if it matches any of the include
+// rules (or there are none) and none of the exclude rules. If
+// staying in the seed path, it also needs to be under one
+// of the seed paths
+func (c *Crawler) inScope(u *url.URL) bool {
+	if c.config.StayInSeedPath && !c.inSeedPath(u) {
+		return false
+	}
+
+	included := len(c.config.Include) == 0
+	for _, rule := range c.config.Include {
+		if rule.Match(u) {
+			included = true
+			break
+		}
+	}
+
+	if !included {
+		return false
+	}
+
+	for _, rule := range c.config.Exclude {
+		if rule.Match(u) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// inSeedPath checks if the link is the path of any of the
+// seeds or under it, matching only whole path segments, e.g.
+// a seed of `/docs` has `/docs/guide` but not `/docs-old`
+func (c *Crawler) inSeedPath(u *url.URL) bool {
+	for _, seedPath := range c.seedPaths {
+		if underPath(u.Path, seedPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// underPath checks if the path is the prefix path or under it,
+// the trailing slash of the prefix path is ignored
+func underPath(p, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
@@ -0,0 +1,88 @@
+package crawler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func TestParseScopeRule(t *testing.T) {
+	tc := []struct {
+		Rule    string
+		Path    string
+		Matches bool
+	}{
+		{Rule: "/docs/", Path: "/docs/guide/", Matches: true},
+		{Rule: "/docs/", Path: "/blog/", Matches: false},
+		{Rule: "prefix:/cart", Path: "/cart/checkout/", Matches: true},
+		{Rule: "/docs", Path: "/docs", Matches: true},
+		{Rule: "/docs", Path: "/docs/guide/", Matches: true},
+		{Rule: "/docs", Path: "/docs-old/", Matches: false},
+		{Rule: "/docs/", Path: "/docs", Matches: false},
+		{Rule: "glob:/*/cart/", Path: "/en/cart/", Matches: true},
+		{Rule: "glob:/*/cart/", Path: "/en/gb/cart/", Matches: false},
+		{Rule: "regex:^/search", Path: "/search/", Matches: true},
+		{Rule: "regex:^/search", Path: "/docs/search/", Matches: false},
+		{Rule: "regex:\\.pdf/?$", Path: "/files/report.pdf", Matches: true},
+	}
+
+	for _, v := range tc {
+		rule, err := ParseScopeRule(v.Rule)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		assert.Equal(t, v.Matches, rule.Match(&url.URL{Path: v.Path}), v.Rule+" "+v.Path)
+	}
+}
+
+func TestParseScopeRule_Err(t *testing.T) {
+	_, err := ParseScopeRule("regex:(")
+	assert.Error(t, err)
+
+	_, err = ParseScopeRule("glob:[")
+	assert.Error(t, err)
+}
+
+func TestCrawler_inScope(t *testing.T) {
+	c := NewWithConfig(url.URL{}, Config{
+		Include: []ScopeRule{PathPrefix("/docs/")},
+		Exclude: []ScopeRule{PathGlob("/docs/*/print/")},
+	})
+
+	assert.True(t, c.inScope(&url.URL{Path: "/docs/"}))
+	assert.True(t, c.inScope(&url.URL{Path: "/docs/guide/"}))
+	assert.False(t, c.inScope(&url.URL{Path: "/docs/guide/print/"}))
+	assert.False(t, c.inScope(&url.URL{Path: "/blog/"}))
+}
+
+func TestCrawler_inSeedPath(t *testing.T) {
+	c := NewWithSeeds([]url.URL{{Path: "/docs"}, {Path: "/blog/"}}, Config{StayInSeedPath: true})
+
+	assert.True(t, c.inScope(&url.URL{Path: "/docs"}))
+	assert.True(t, c.inScope(&url.URL{Path: "/docs/"}))
+	assert.True(t, c.inScope(&url.URL{Path: "/docs/guide/"}))
+	assert.False(t, c.inScope(&url.URL{Path: "/docs-old/"}))
+	assert.False(t, c.inScope(&url.URL{Path: "/"}))
+	assert.True(t, c.inScope(&url.URL{Path: "/blog"}))
+	assert.True(t, c.inScope(&url.URL{Path: "/blog/post/"}))
+	assert.False(t, c.inScope(&url.URL{Path: "/blogroll/"}))
+
+	c = NewWithSeeds([]url.URL{{Path: "/"}}, Config{StayInSeedPath: true})
+	assert.True(t, c.inScope(&url.URL{Path: "/anything/"}))
+}
+
+func TestCrawler_inSeedPath_TrailingSlashRemove(t *testing.T) {
+	seeds := []url.URL{{Scheme: "http", Host: "example.com", Path: "/product/docs/"}}
+	c := NewWithSeeds(seeds, Config{
+		StayInSeedPath: true,
+		TrailingSlash:  TrailingSlashRemove,
+	})
+
+	assert.Equal(t, "/product/docs/", seeds[0].Path)
+	assert.Equal(t, "/product/docs", c.seeds[0].Path)
+	assert.True(t, c.inScope(&url.URL{Path: "/product/docs"}))
+	assert.True(t, c.inScope(&url.URL{Path: "/product/docs/intro"}))
+	assert.False(t, c.inScope(&url.URL{Path: "/product/pricing"}))
+}
@@ -8,8 +8,18 @@ type SiteMap map[string]*Page
 // PathsCrawled returns a slice of the paths crawled
 func (s SiteMap) PathsCrawled() []string {
 	var paths []string
-	for url := range s {
-		paths = append(paths, url)
+	for _, page := range s {
+		paths = append(paths, page.URL.Path)
 	}
 	return paths
+}
+
+// URLsCrawled returns a slice of the canonical urls crawled,
+// these are the keys of the sitemap
+func (s SiteMap) URLsCrawled() []string {
+	var urls []string
+	for url := range s {
+		urls = append(urls, url)
+	}
+	return urls
 }
\ No newline at end of file
@@ -0,0 +1,155 @@
+package crawler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"golang.org/x/net/html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+)
+
+// notFoundProbeBytes is the number of random bytes in
+// the path of the url requested to learn the not found page
+const notFoundProbeBytes = 16
+
+// notFoundTitle matches the titles of not found pages
+var notFoundTitle = regexp.MustCompile(`(?i)(^|\W)(404|not found|page (does not|doesn't) exist|(cannot|can't|could not|couldn't) be found|no longer (exists|available))(\W|$)`)
+
+// notFoundPage is what a host serves for a url that doesn't
+// exist, `url` being where the probe ended up after redirects
+type notFoundPage struct {
+	url  string
+	info PageInfo
+}
+
+// notFoundEntry is a host in the not found page cache,
+// probed once by the first page on the host to be checked
+type notFoundEntry struct {
+	once sync.Once
+	page *notFoundPage
+}
+
+// notFoundFor passes back the not found page of the scheme and
+// host of the url, probing for it if it's not been probed yet.
+// nil is passed back if the host responds to the probe with an
+// error, i.e. it doesn't serve soft 404s
+func (c *Crawler) notFoundFor(ctx context.Context, u *url.URL) *notFoundPage {
+	key := u.Scheme + "://" + u.Host
+
+	c.notFoundMu.Lock()
+	if c.notFound == nil {
+		c.notFound = make(map[string]*notFoundEntry)
+	}
+	entry, ok := c.notFound[key]
+	if !ok {
+		entry = &notFoundEntry{}
+		c.notFound[key] = entry
+	}
+	c.notFoundMu.Unlock()
+
+	entry.once.Do(func() {
+		entry.page = c.probeNotFound(ctx, u)
+	})
+
+	return entry.page
+}
+
+// probeNotFound requests a random url on the host of the url that
+// can't exist, passing back the page if it was served successfully.
+// The probe is paced by the rate limiter like the pages, and isn't
+// made if the robots.txt disallows it
+func (c *Crawler) probeNotFound(ctx context.Context, u *url.URL) *notFoundPage {
+	random := make([]byte, notFoundProbeBytes)
+	if _, err := rand.Read(random); err != nil {
+		return nil
+	}
+
+	probe := url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/" + hex.EncodeToString(random) + "/"}
+	if allowed, _ := c.robotsAllowed(ctx, &probe); !allowed {
+		return nil
+	}
+
+	release, err := c.limiter.wait(ctx, &probe)
+	if err != nil {
+		return nil
+	}
+	defer release()
+
+	request, err := c.newRequest(ctx, http.MethodGet, probe.String(), nil)
+	if err != nil {
+		return nil
+	}
+
+	client, err := c.httpClient()
+	if err != nil {
+		return nil
+	}
+
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if checkResponse(resp) != nil {
+		return nil
+	}
+
+	page := &notFoundPage{url: resp.Request.URL.String()}
+	info := &infoParser{info: &page.info}
+	tokenizer := html.NewTokenizer(resp.Body)
+	for {
+		t := tokenizer.Next()
+		if t == html.ErrorToken {
+			if tokenizer.Err() != io.EOF {
+				return nil
+			}
+			break
+		}
+		info.token(t, tokenizer.Token(), resp.Request.URL)
+	}
+	info.finish()
+
+	return page
+}
+
+// checkSoftNotFound flags the page as a soft 404 if its title
+// is that of a not found page, or its text is the same, or nearly
+// the same, as the page the host serves for urls that don't exist.
+// Redirects are checked by the page they end up at, a redirect
+// to the not found page being a soft 404. The home page is never
+// a soft 404, as some hosts serve it, or redirect to it, for every
+// url that doesn't exist
+func (p *Page) checkSoftNotFound(ctx context.Context) {
+	final := p.URL
+	if p.IsRedirect && p.RedirectsTo != nil {
+		final = *p.RedirectsTo
+	}
+
+	if final.Path == "/" {
+		return
+	}
+
+	if notFoundTitle.MatchString(p.Info.Title) {
+		p.SoftNotFound = true
+		return
+	}
+
+	notFound := p.crawler.notFoundFor(ctx, &final)
+	if notFound == nil {
+		return
+	}
+
+	if notFound.url == final.String() {
+		p.SoftNotFound = p.IsRedirect
+		return
+	}
+
+	p.SoftNotFound = notFound.info.WordCount > 0 && p.Info.WordCount > 0 &&
+		(p.Info.ContentHash == notFound.info.ContentHash ||
+			similarity(p.Info.SimHash, notFound.info.SimHash) >= DefaultDuplicateThreshold)
+}
@@ -0,0 +1,199 @@
+package crawler
+
+import (
+	"context"
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCrawler_RunWithSoftNotFound(t *testing.T) {
+	s := test.NewSoftNotFoundServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:         1,
+		IgnoreRobotsTxt:    true,
+		KeepErrorPages:     true,
+		DetectSoftNotFound: true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	soft := make(map[string]bool)
+	for _, page := range crawler.SiteMap {
+		soft[page.URL.Path] = page.SoftNotFound
+	}
+	assert.Equal(t, map[string]bool{
+		"/":              false,
+		"/products/":     false,
+		"/discontinued/": true,
+		"/oops/":         true,
+		"/gone/":         false,
+	}, soft)
+
+	assert.Equal(t, []BrokenLink{
+		{
+			Source:          s.URL + "/",
+			Target:          s.URL + "/discontinued/",
+			AnchorText:      "Discontinued range",
+			StatusCode:      http.StatusOK,
+			SoftNotFound:    true,
+			LinkedFromCount: 1,
+		},
+		{
+			Source:          s.URL + "/",
+			Target:          s.URL + "/gone/",
+			AnchorText:      "Gone",
+			StatusCode:      http.StatusNotFound,
+			ErrorCategory:   ErrorClient,
+			Error:           "404 Not Found",
+			LinkedFromCount: 1,
+		},
+		{
+			Source:          s.URL + "/",
+			Target:          s.URL + "/oops/",
+			AnchorText:      "Oops",
+			StatusCode:      http.StatusOK,
+			SoftNotFound:    true,
+			LinkedFromCount: 1,
+		},
+	}, crawler.SiteMap.BrokenLinks())
+	assert.False(t, crawler.SiteMap[s.URL+"/oops/"].indexable())
+}
+
+func TestCrawler_RunWithSoftNotFound_Redirect(t *testing.T) {
+	s := test.NewSoftNotFoundRedirectServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:         1,
+		IgnoreRobotsTxt:    true,
+		KeepErrorPages:     true,
+		DetectSoftNotFound: true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	soft := make(map[string]bool)
+	for _, page := range crawler.SiteMap {
+		soft[page.URL.Path] = page.SoftNotFound
+	}
+	assert.Equal(t, map[string]bool{
+		"/":           false,
+		"/products/":  false,
+		"/moved/":     false,
+		"/old/":       true,
+		"/not-found/": false,
+	}, soft)
+}
+
+func TestCrawler_RunWithoutSoftNotFound(t *testing.T) {
+	s := test.NewSoftNotFoundServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	for _, page := range crawler.SiteMap {
+		assert.False(t, page.SoftNotFound, page.URL.Path)
+	}
+	assert.Empty(t, crawler.SiteMap.BrokenLinks())
+}
+
+func TestCrawler_RunWithSoftNotFound_HomeForEveryURL(t *testing.T) {
+	s := test.NewStatusServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:         1,
+		IgnoreRobotsTxt:    true,
+		DetectSoftNotFound: true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	for _, page := range crawler.SiteMap {
+		assert.False(t, page.SoftNotFound, page.URL.Path)
+	}
+}
+
+func TestNotFoundTitle(t *testing.T) {
+	for _, title := range []string{
+		"404",
+		"Error 404 - Example",
+		"Page Not Found | Shop",
+		"This page doesn't exist",
+		"The product could not be found",
+		"Sorry, this item is no longer available",
+	} {
+		assert.True(t, notFoundTitle.MatchString(title), title)
+	}
+
+	for _, title := range []string{
+		"Home",
+		"Products 4040",
+		"Found it!",
+		"",
+	} {
+		assert.False(t, notFoundTitle.MatchString(title), title)
+	}
+}
+
+func TestCrawler_RunWithSoftNotFound_RateLimited(t *testing.T) {
+	log := &test.RequestLog{}
+	s := test.NewRateServer(log, 20*time.Millisecond)
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:         10,
+		IgnoreRobotsTxt:    true,
+		RequestsPerSecond:  20,
+		MaxConnsPerHost:    1,
+		DetectSoftNotFound: true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	// the pages and the probe for the not found page
+	assert.Len(t, crawler.SiteMap, 7)
+	assert.Len(t, log.Times(), 8)
+	assert.Equal(t, 1, log.MaxActive())
+	assertSpacing(t, log.Times(), 50*time.Millisecond)
+}
+
+func TestCrawler_RunWithSoftNotFound_ProbeDisallowed(t *testing.T) {
+	s := test.NewSoftNotFoundServer()
+	s.WithRobotsStatus(http.StatusOK)
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:         1,
+		UserAgent:          "test-robot",
+		DetectSoftNotFound: true,
+	})
+	crawler.limiter = crawler.newRateLimiter()
+	assert.Nil(t, crawler.probeNotFound(context.Background(), s.Url))
+}
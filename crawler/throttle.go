@@ -0,0 +1,182 @@
+package crawler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	headerRetryAfter = "Retry-After"
+
+	// maxRetryAfter caps how long a `Retry-After`
+	// can pause the requests to a host for
+	maxRetryAfter = 2 * time.Minute
+
+	// slowFactor is how many times slower than the
+	// quickest the average response time can get
+	// before the crawl is throttled
+	slowFactor = 2
+
+	// minSlowLatency is the average response time below
+	// which the crawl is never throttled for being slow
+	minSlowLatency = 50 * time.Millisecond
+
+	// latencyWarmup is the number of responses needed
+	// before the average response time is trusted
+	latencyWarmup = 3
+)
+
+// Progress is the state of a running crawl
+type Progress struct {
+	// Crawled is the number of pages crawled so far
+	Crawled int
+
+	// Queued is the number of pages waiting to
+	// be crawled or being crawled
+	Queued int
+
+	// Concurrency is the number of workers crawling,
+	// lowered when the crawl is being throttled
+	Concurrency int
+
+	// MaxConcurrency is the number of workers the
+	// crawl can go up to
+	MaxConcurrency int
+}
+
+// throttle adjusts the concurrency of the crawl in the
+// spirit of AIMD, halving it when the server responds
+// with a 429 or 503, times out or slows down, and adding
+// a worker back after each round of healthy responses
+type throttle struct {
+	level int
+	max   int
+
+	// healthy is the number of healthy responses since
+	// the level last changed
+	healthy int
+
+	// sinceDecrease is the number of responses since the
+	// level was last lowered, it's only lowered once per
+	// round so a burst of errors doesn't floor it
+	sinceDecrease int
+
+	// avg is the moving average of the response times, and
+	// fastest is the quickest it's been once warmed up
+	avg     time.Duration
+	fastest time.Duration
+	samples int
+}
+
+// newThrottle passes back a throttle starting
+// at the max concurrency
+func newThrottle(max int) *throttle {
+	return &throttle{
+		level:         max,
+		max:           max,
+		sinceDecrease: max,
+	}
+}
+
+// observe records the response of the page, passing back
+// the new concurrency level and whether it changed
+func (t *throttle) observe(p *Page) (int, bool) {
+	t.sinceDecrease++
+
+	if t.struggling(p) {
+		if t.sinceDecrease < t.level {
+			return t.level, false
+		}
+
+		t.sinceDecrease = 0
+		t.healthy = 0
+		level := t.level / 2
+		if level < 1 {
+			level = 1
+		}
+
+		changed := level != t.level
+		t.level = level
+		return t.level, changed
+	}
+
+	t.healthy++
+	if t.healthy < t.level || t.level >= t.max {
+		return t.level, false
+	}
+
+	t.healthy = 0
+	t.level++
+	return t.level, true
+}
+
+// struggling checks if the response of the page means the
+// server isn't coping with the crawl, only the successful
+// responses count towards the response times as errors
+// are often passed back quicker
+func (t *throttle) struggling(p *Page) bool {
+	switch {
+	case p.Response.StatusCode == http.StatusTooManyRequests,
+		p.Response.StatusCode == http.StatusServiceUnavailable,
+		p.Response.ErrorCategory == ErrorTimeout:
+		return true
+	case p.Response.ErrorCategory != "":
+		return false
+	}
+
+	return t.slow(p.Response.TimeToFirstByte)
+}
+
+// slow records the response time, checking if the average
+// has climbed too far above the quickest it's been
+func (t *throttle) slow(ttfb time.Duration) bool {
+	if ttfb <= 0 {
+		return false
+	}
+
+	t.samples++
+	if t.avg == 0 {
+		t.avg = ttfb
+	} else {
+		t.avg = (t.avg*3 + ttfb) / 4
+	}
+
+	if t.samples < latencyWarmup {
+		return false
+	}
+
+	if t.fastest == 0 || t.avg < t.fastest {
+		t.fastest = t.avg
+	}
+
+	return t.avg > minSlowLatency && t.avg > t.fastest*slowFactor
+}
+
+// parseRetryAfter parses the `Retry-After` header, which is
+// either a number of seconds or a date, passing back 0 if
+// it's missing or invalid
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = date.Sub(now)
+	}
+
+	if wait < 0 {
+		return 0
+	}
+
+	if wait > maxRetryAfter {
+		return maxRetryAfter
+	}
+
+	return wait
+}
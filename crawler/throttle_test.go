@@ -0,0 +1,162 @@
+package crawler
+
+import (
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// requestTime passes back when the request for the
+// path was made, failing the test if there wasn't one
+func requestTime(t *testing.T, log *test.RequestLog, path string) time.Time {
+	t.Helper()
+	times := log.Times()
+	for i, p := range log.Paths() {
+		if p == path {
+			return times[i]
+		}
+	}
+
+	t.Fatalf("%s wasn't requested", path)
+	return time.Time{}
+}
+
+func TestCrawler_RunWithRetryAfter_NotThrottled(t *testing.T) {
+	log := &test.RequestLog{}
+	s := test.NewThrottleServer(log)
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      4,
+		IgnoreRobotsTxt: true,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	limited := requestTime(t, log, "/1/")
+	next := crawler.limiter.host(s.Url).next
+	assert.False(t, next.Before(limited.Add(time.Second)), "retry after not honored")
+}
+
+func throttlePage(status int, ttfb time.Duration) *Page {
+	return &Page{Response: Response{StatusCode: status, TimeToFirstByte: ttfb}}
+}
+
+func TestThrottle_Observe(t *testing.T) {
+	th := newThrottle(8)
+
+	level, changed := th.observe(throttlePage(http.StatusTooManyRequests, 0))
+	assert.Equal(t, 4, level)
+	assert.True(t, changed)
+
+	// the level is only lowered once per round
+	level, changed = th.observe(throttlePage(http.StatusServiceUnavailable, 0))
+	assert.Equal(t, 4, level)
+	assert.False(t, changed)
+
+	for i := 0; i < 3; i++ {
+		_, changed = th.observe(throttlePage(http.StatusOK, 0))
+		assert.False(t, changed)
+	}
+
+	level, changed = th.observe(throttlePage(http.StatusOK, 0))
+	assert.Equal(t, 5, level)
+	assert.True(t, changed)
+
+	level, _ = th.observe(&Page{Response: Response{ErrorCategory: ErrorTimeout}})
+	assert.Equal(t, 2, level)
+}
+
+func TestThrottle_Observe_Max(t *testing.T) {
+	th := newThrottle(2)
+	for i := 0; i < 10; i++ {
+		level, changed := th.observe(throttlePage(http.StatusOK, 0))
+		assert.Equal(t, 2, level)
+		assert.False(t, changed)
+	}
+}
+
+func TestThrottle_Observe_Floor(t *testing.T) {
+	th := newThrottle(1)
+	level, changed := th.observe(throttlePage(http.StatusTooManyRequests, 0))
+	assert.Equal(t, 1, level)
+	assert.False(t, changed)
+}
+
+func TestThrottle_Observe_Slow(t *testing.T) {
+	th := newThrottle(2)
+	for i := 0; i < latencyWarmup; i++ {
+		th.observe(throttlePage(http.StatusOK, 40*time.Millisecond))
+	}
+
+	level := 2
+	for i := 0; i < 5 && level == 2; i++ {
+		level, _ = th.observe(throttlePage(http.StatusOK, 400*time.Millisecond))
+	}
+	assert.Equal(t, 1, level)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2019, 3, 13, 10, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 30*time.Second, parseRetryAfter("30", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter("Wed, 13 Mar 2019 10:01:30 GMT", now))
+	assert.Equal(t, maxRetryAfter, parseRetryAfter("3600", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("Wed, 13 Mar 2019 09:00:00 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+}
+
+func TestCrawler_RunWithAdaptiveThrottle(t *testing.T) {
+	log := &test.RequestLog{}
+	s := test.NewThrottleServer(log)
+	s.Start()
+	defer s.Close()
+
+	var mu sync.Mutex
+	var progress []Progress
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:       4,
+		IgnoreRobotsTxt:  true,
+		AdaptiveThrottle: true,
+		OnProgress: func(p Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			progress = append(progress, p)
+		},
+	})
+
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Len(t, crawler.SiteMap, 6)
+
+	// the host is paused for the second asked for by the 429
+	// from when its response was seen, so past when it was sent
+	limited := requestTime(t, log, "/1/")
+	next := crawler.limiter.host(s.Url).next
+	assert.False(t, next.Before(limited.Add(time.Second)), "retry after not honored")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, progress, 7)
+	assert.Equal(t, 7, progress[len(progress)-1].Crawled)
+	assert.Equal(t, 0, progress[len(progress)-1].Queued)
+
+	lowest := 4
+	for _, p := range progress {
+		assert.Equal(t, 4, p.MaxConcurrency)
+		if p.Concurrency < lowest {
+			lowest = p.Concurrency
+		}
+	}
+	assert.Equal(t, 2, lowest)
+}
@@ -0,0 +1,163 @@
+package crawler
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultRequestTimeout = 10 * time.Second
+	defaultConnectTimeout = 30 * time.Second
+	defaultTLSTimeout     = 10 * time.Second
+	dialKeepAlive         = 30 * time.Second
+)
+
+// httpClient passes back the client shared by all of the
+// requests of the crawl, building it from the config the
+// first time it's needed
+func (c *Crawler) httpClient() (*http.Client, error) {
+	c.clientOnce.Do(func() {
+		c.client, c.clientErr = c.config.newHTTPClient()
+	})
+
+	return c.client, c.clientErr
+}
+
+// newHTTPClient builds the http client from the config, a
+// client passed in is copied so that redirects can be
+// recorded without changing it, its own `CheckRedirect` is
+// still called after the redirect has been recorded. A
+// cookie jar is added if the client doesn't have one
+func (c Config) newHTTPClient() (*http.Client, error) {
+	if c.HTTPClient != nil {
+		client := *c.HTTPClient
+		client.CheckRedirect = chainCheckRedirect(c.HTTPClient.CheckRedirect)
+		if client.Jar == nil {
+			jar, err := c.newCookieJar()
+			if err != nil {
+				return nil, err
+			}
+			client.Jar = jar
+		}
+		return &client, nil
+	}
+
+	jar, err := c.newCookieJar()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := c.Transport
+	if transport == nil {
+		transport, err = c.newTransport()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	timeout := c.RequestTimeout
+	if timeout == 0 {
+		timeout = defaultRequestTimeout
+	}
+
+	return &http.Client{
+		Transport:     transport,
+		Timeout:       timeout,
+		CheckRedirect: checkRedirect,
+		Jar:           jar,
+	}, nil
+}
+
+// chainCheckRedirect records the redirect with checkRedirect
+// and then calls the check of the client passed in, if any,
+// so it can still stop following redirects
+func chainCheckRedirect(check func(*http.Request, []*http.Request) error) func(*http.Request, []*http.Request) error {
+	if check == nil {
+		return checkRedirect
+	}
+
+	return func(req *http.Request, via []*http.Request) error {
+		if err := checkRedirect(req, via); err != nil {
+			return err
+		}
+		return check(req, via)
+	}
+}
+
+// newTransport builds the transport from the timeouts,
+// proxy and tls settings of the config
+func (c Config) newTransport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	connectTimeout := c.ConnectTimeout
+	if connectTimeout == 0 {
+		connectTimeout = defaultConnectTimeout
+	}
+	transport.DialContext = (&net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: dialKeepAlive,
+	}).DialContext
+
+	transport.TLSHandshakeTimeout = c.TLSHandshakeTimeout
+	if transport.TLSHandshakeTimeout == 0 {
+		transport.TLSHandshakeTimeout = defaultTLSTimeout
+	}
+	transport.ResponseHeaderTimeout = c.ResponseHeaderTimeout
+
+	transport.MaxIdleConnsPerHost = c.MaxIdleConnsPerHost
+	if transport.MaxIdleConnsPerHost == 0 {
+		transport.MaxIdleConnsPerHost = c.MaxWorkers
+	}
+
+	if c.Proxy != nil {
+		transport.Proxy = http.ProxyURL(c.Proxy)
+	}
+
+	tlsConfig, err := c.newTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return transport, nil
+}
+
+// newTLSConfig builds the tls config with the ca bundle
+// added to the system roots and the client certificate
+func (c Config) newTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
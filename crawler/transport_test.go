@@ -0,0 +1,306 @@
+package crawler
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/m1/smap/test"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingTransport counts the requests made through it
+// along with the user agents they were made with
+type countingTransport struct {
+	mu         sync.Mutex
+	paths      []string
+	userAgents map[string]bool
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.paths = append(t.paths, r.URL.Path)
+	if t.userAgents == nil {
+		t.userAgents = make(map[string]bool)
+	}
+	t.userAgents[r.Header.Get(headerUserAgent)] = true
+	t.mu.Unlock()
+
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// writePEM writes the pem block to the file in the dir
+func writePEM(t *testing.T, dir string, name string, block *pem.Block) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeClientCert writes a self signed client certificate
+// and its key to the dir, passing back their paths
+func writeClientCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "smap"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return writePEM(t, dir, "client.pem", &pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		writePEM(t, dir, "client.key", &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestCrawler_RunWithTransport(t *testing.T) {
+	s := test.NewServer()
+	s.WithOkayRobots()
+	s.Start()
+	defer s.Close()
+
+	transport := &countingTransport{}
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers: 1,
+		UserAgent:  "test-robot",
+		Transport:  transport,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, "/robots.txt", transport.paths[0])
+	assert.Len(t, transport.paths, len(crawler.SiteMap)+1)
+	assert.Equal(t, map[string]bool{"test-robot": true}, transport.userAgents)
+}
+
+func TestCrawler_RunWithHTTPClient(t *testing.T) {
+	s := test.NewRedirectServer()
+	s.Start()
+	defer s.Close()
+
+	transport := &countingTransport{}
+	client := &http.Client{Transport: transport}
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		HTTPClient:      client,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.NotEmpty(t, transport.paths)
+	assert.Nil(t, client.CheckRedirect)
+	assert.Len(t, crawler.SiteMap[s.URL+"/1/"].RedirectChain, 1)
+}
+
+func TestCrawler_RunWithHTTPClient_CheckRedirect(t *testing.T) {
+	s := test.NewRedirectServer()
+	s.Start()
+	defer s.Close()
+
+	var checked []string
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			checked = append(checked, req.URL.Path)
+			return http.ErrUseLastResponse
+		},
+	}
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		HTTPClient:      client,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.NotEmpty(t, checked)
+	assert.Len(t, crawler.SiteMap[s.URL+"/1/"].RedirectChain, 1)
+	assert.Nil(t, crawler.SiteMap[s.URL+"/1/"].RedirectsTo)
+}
+
+func TestCrawler_RunReusesConnections(t *testing.T) {
+	log := &test.RequestLog{}
+	s := test.NewRateServer(log, 0)
+	s.Start()
+	defer s.Close()
+
+	crawler := New(*s.Url, true, 1, "test-robot")
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Len(t, log.Paths(), 7)
+	assert.Equal(t, 1, log.Remotes())
+}
+
+func TestCrawler_RunWithRequestTimeout(t *testing.T) {
+	s := test.NewSlowServer()
+	s.Start()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		KeepErrorPages:  true,
+		RequestTimeout:  50 * time.Millisecond,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, ErrorTimeout, crawler.SiteMap[s.URL+"/1/"].Response.ErrorCategory)
+}
+
+func TestCrawler_RunWithProxy(t *testing.T) {
+	var mu sync.Mutex
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r.URL.String())
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("<a href=\"/1/\">1</a>"))
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	u, _ := url.Parse("http://example.invalid/")
+	crawler := NewWithConfig(*u, Config{
+		MaxWorkers:      1,
+		IgnoreRobotsTxt: true,
+		Proxy:           proxyURL,
+	})
+	err := crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, []string{"http://example.invalid/", "http://example.invalid/1/"}, proxied)
+	assert.Len(t, crawler.SiteMap, 2)
+}
+
+func TestCrawler_RunWithTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := test.NewServer()
+	s.StartTLS()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{MaxWorkers: 1, IgnoreRobotsTxt: true, KeepErrorPages: true})
+	err = crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, ErrorTLS, crawler.SiteMap[s.URL+"/"].Response.ErrorCategory)
+
+	ca := writePEM(t, dir, "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	crawler = NewWithConfig(*s.Url, Config{MaxWorkers: 1, IgnoreRobotsTxt: true, CAFile: ca})
+	err = crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, crawler.SiteMap, 3)
+
+	crawler = NewWithConfig(*s.Url, Config{MaxWorkers: 1, IgnoreRobotsTxt: true, InsecureSkipVerify: true})
+	err = crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, crawler.SiteMap, 3)
+}
+
+func TestCrawler_RunWithClientCert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := test.NewServer()
+	s.StartMutualTLS()
+	defer s.Close()
+
+	crawler := NewWithConfig(*s.Url, Config{
+		MaxWorkers:         1,
+		IgnoreRobotsTxt:    true,
+		KeepErrorPages:     true,
+		InsecureSkipVerify: true,
+	})
+	err = crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+	assert.NotEmpty(t, crawler.SiteMap[s.URL+"/"].Response.ErrorCategory)
+
+	cert, key := writeClientCert(t, dir)
+	crawler = NewWithConfig(*s.Url, Config{
+		MaxWorkers:         1,
+		IgnoreRobotsTxt:    true,
+		InsecureSkipVerify: true,
+		CertFile:           cert,
+		KeyFile:            key,
+	})
+	err = crawler.Run()
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, crawler.SiteMap, 3)
+}
+
+func TestCrawler_RunWithBadCAFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := filepath.Join(dir, "ca.pem")
+	if err := ioutil.WriteFile(ca, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse("https://example.invalid/")
+	crawler := NewWithConfig(*u, Config{MaxWorkers: 1, CAFile: ca})
+	assert.EqualError(t, crawler.Run(), "no certificates found in "+ca)
+
+	crawler = NewWithConfig(*u, Config{MaxWorkers: 1, CertFile: filepath.Join(dir, "missing.pem")})
+	assert.Error(t, crawler.Run())
+}
@@ -0,0 +1,204 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// sitemapMaxURLs and sitemapMaxBytes are the limits of a
+	// single sitemap file from the sitemaps.org protocol
+	sitemapMaxURLs  = 50000
+	sitemapMaxBytes = 50 * 1024 * 1024
+
+	sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	sitemapFile      = "sitemap.xml"
+	sitemapPartFile  = "sitemap-%d.xml"
+	gzipExt          = ".gz"
+
+	headerLastModified = "Last-Modified"
+)
+
+var (
+	xmlUrlsetStart = []byte(xml.Header + `<urlset xmlns="` + sitemapNamespace + `">` + "\n")
+	xmlUrlsetEnd   = []byte("</urlset>\n")
+	xmlIndexStart  = []byte(xml.Header + `<sitemapindex xmlns="` + sitemapNamespace + `">` + "\n")
+	xmlIndexEnd    = []byte("</sitemapindex>\n")
+)
+
+// XMLOptions are the options for writing the
+// sitemaps.org xml files
+type XMLOptions struct {
+	// BaseURL is the url the sitemap files are served
+	// from, used for the locations in the sitemap index
+	BaseURL *url.URL
+
+	// Gzip compresses the files, adding `.gz`
+	// to their names
+	Gzip bool
+
+	// MaxURLs and MaxBytes are the limits of a single
+	// sitemap file before it's split, defaults to the
+	// protocol limits of 50,000 urls and 50MB
+	MaxURLs  int
+	MaxBytes int
+}
+
+type xmlURL struct {
+	XMLName xml.Name `xml:"url"`
+	Loc     string   `xml:"loc"`
+	LastMod string   `xml:"lastmod,omitempty"`
+}
+
+type xmlSitemap struct {
+	XMLName xml.Name `xml:"sitemap"`
+	Loc     string   `xml:"loc"`
+}
+
+// indexable checks if the page should be listed in the xml
+// sitemap, pages that redirect, had errors, are noindex
+// or are assets are left out
+func (p *Page) indexable() bool {
+	return !p.IsRedirect && !p.Asset && p.Response.ErrorCategory == "" && !p.NoIndex && !p.SoftNotFound
+}
+
+// lastModified passes back the `Last-Modified` header
+// of the page in the W3C datetime format
+func (p *Page) lastModified() string {
+	t, err := http.ParseTime(p.Response.Headers[headerLastModified])
+	if err != nil {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// WriteXML writes the indexable pages to sitemaps.org xml files
+// in the dir, passing back the paths of the files written. If
+// the pages don't fit in a single file they are split into
+// several, with a `sitemap.xml` index listing them
+func (s SiteMap) WriteXML(dir string, opts XMLOptions) ([]string, error) {
+	if opts.MaxURLs <= 0 || opts.MaxURLs > sitemapMaxURLs {
+		opts.MaxURLs = sitemapMaxURLs
+	}
+
+	if opts.MaxBytes <= 0 || opts.MaxBytes > sitemapMaxBytes {
+		opts.MaxBytes = sitemapMaxBytes
+	}
+
+	parts, err := s.xmlParts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parts) == 1 {
+		name, err := writeXMLFile(dir, sitemapFile, parts[0], opts.Gzip)
+		if err != nil {
+			return nil, err
+		}
+		return []string{name}, nil
+	}
+
+	if opts.BaseURL == nil {
+		return nil, fmt.Errorf("a base url is needed for the sitemap index of %d files", len(parts))
+	}
+
+	var files []string
+	index := bytes.NewBuffer(xmlIndexStart)
+	for i, part := range parts {
+		name, err := writeXMLFile(dir, fmt.Sprintf(sitemapPartFile, i+1), part, opts.Gzip)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, name)
+
+		loc, err := opts.BaseURL.Parse(filepath.Base(name))
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err := xml.Marshal(xmlSitemap{Loc: loc.String()})
+		if err != nil {
+			return nil, err
+		}
+		index.Write(entry)
+		index.WriteString("\n")
+	}
+	index.Write(xmlIndexEnd)
+
+	name, err := writeXMLFile(dir, sitemapFile, index.Bytes(), opts.Gzip)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]string{name}, files...), nil
+}
+
+// xmlParts encodes the indexable pages sorted by url into
+// urlsets, starting a new one when the limits are hit
+func (s SiteMap) xmlParts(opts XMLOptions) ([][]byte, error) {
+	var urls []string
+	for key, page := range s {
+		if page.indexable() {
+			urls = append(urls, key)
+		}
+	}
+	sort.Strings(urls)
+
+	var parts [][]byte
+	part := bytes.NewBuffer(xmlUrlsetStart)
+	count := 0
+	for _, key := range urls {
+		entry, err := xml.Marshal(xmlURL{
+			Loc:     key,
+			LastMod: s[key].lastModified(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		entry = append(entry, '\n')
+
+		if count > 0 && (count >= opts.MaxURLs ||
+			part.Len()+len(entry)+len(xmlUrlsetEnd) > opts.MaxBytes) {
+			part.Write(xmlUrlsetEnd)
+			parts = append(parts, part.Bytes())
+			part = bytes.NewBuffer(xmlUrlsetStart)
+			count = 0
+		}
+
+		part.Write(entry)
+		count++
+	}
+	part.Write(xmlUrlsetEnd)
+
+	return append(parts, part.Bytes()), nil
+}
+
+// writeXMLFile writes the file to the dir, gzipping it if
+// needed, and passes back the path it was written to
+func writeXMLFile(dir string, name string, data []byte, gz bool) (string, error) {
+	if gz {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return "", err
+		}
+		if err := w.Close(); err != nil {
+			return "", err
+		}
+
+		data = buf.Bytes()
+		name += gzipExt
+	}
+
+	path := filepath.Join(dir, name)
+	return path, ioutil.WriteFile(path, data, 0644)
+}
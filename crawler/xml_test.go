@@ -0,0 +1,175 @@
+package crawler
+
+import (
+	"compress/gzip"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func xmlSiteMap(n int) SiteMap {
+	s := SiteMap{}
+	for i := 0; i < n; i++ {
+		u, _ := url.Parse(fmt.Sprintf("https://example.com/page-%03d/", i))
+		s[u.String()] = &Page{URL: *u}
+	}
+	return s
+}
+
+func readXML(t *testing.T, path string) string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestSiteMap_WriteXML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := xmlSiteMap(1)
+	s["https://example.com/page-000/"].Response.Headers = map[string]string{
+		"Last-Modified": "Wed, 21 Oct 2015 07:28:00 GMT",
+	}
+
+	redirect, _ := url.Parse("https://example.com/old/")
+	s[redirect.String()] = &Page{URL: *redirect, IsRedirect: true}
+
+	missing, _ := url.Parse("https://example.com/missing/")
+	s[missing.String()] = &Page{URL: *missing, Response: Response{ErrorCategory: ErrorClient}}
+
+	noindex, _ := url.Parse("https://example.com/private/")
+	s[noindex.String()] = &Page{URL: *noindex, NoIndex: true}
+
+	files, err := s.WriteXML(dir, XMLOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{filepath.Join(dir, "sitemap.xml")}, files)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<url><loc>https://example.com/page-000/</loc><lastmod>2015-10-21T07:28:00Z</lastmod></url>
+</urlset>
+`, readXML(t, files[0]))
+}
+
+func TestSiteMap_WriteXML_Escaped(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	u, _ := url.Parse("https://example.com/search/?a=1&b=2")
+	files, err := SiteMap{u.String(): &Page{URL: *u}}.WriteXML(dir, XMLOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Contains(t, readXML(t, files[0]), "<loc>https://example.com/search/?a=1&amp;b=2</loc>")
+}
+
+func TestSiteMap_WriteXML_Split(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base, _ := url.Parse("https://example.com/sitemaps/")
+	files, err := xmlSiteMap(5).WriteXML(dir, XMLOptions{BaseURL: base, MaxURLs: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{
+		filepath.Join(dir, "sitemap.xml"),
+		filepath.Join(dir, "sitemap-1.xml"),
+		filepath.Join(dir, "sitemap-2.xml"),
+		filepath.Join(dir, "sitemap-3.xml"),
+	}, files)
+
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<sitemap><loc>https://example.com/sitemaps/sitemap-1.xml</loc></sitemap>
+<sitemap><loc>https://example.com/sitemaps/sitemap-2.xml</loc></sitemap>
+<sitemap><loc>https://example.com/sitemaps/sitemap-3.xml</loc></sitemap>
+</sitemapindex>
+`, readXML(t, files[0]))
+
+	assert.Equal(t, 2, strings.Count(readXML(t, files[1]), "<url>"))
+	assert.Equal(t, 2, strings.Count(readXML(t, files[2]), "<url>"))
+	assert.Equal(t, 1, strings.Count(readXML(t, files[3]), "<url>"))
+	assert.Contains(t, readXML(t, files[3]), "https://example.com/page-004/")
+}
+
+func TestSiteMap_WriteXML_SplitBytes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base, _ := url.Parse("https://example.com/")
+	files, err := xmlSiteMap(4).WriteXML(dir, XMLOptions{BaseURL: base, MaxBytes: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, len(files) > 2)
+	for _, f := range files[1:] {
+		assert.True(t, len(readXML(t, f)) <= 300)
+	}
+}
+
+func TestSiteMap_WriteXML_SplitNoBase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, err = xmlSiteMap(3).WriteXML(dir, XMLOptions{MaxURLs: 1})
+	assert.Error(t, err)
+}
+
+func TestSiteMap_WriteXML_Gzip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files, err := xmlSiteMap(2).WriteXML(dir, XMLOptions{Gzip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{filepath.Join(dir, "sitemap.xml.gz")}, files)
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, strings.Count(string(b), "<url>"))
+}
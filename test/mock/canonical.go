@@ -0,0 +1,67 @@
+package mock
+
+var (
+	CanonicalIndex = `	<!DOCTYPE html>
+				<html lang="en">
+				<head>
+					<title>Home</title>
+					<link rel="canonical" href="/">
+					<link rel="alternate" hreflang="en" href="/">
+					<link rel="alternate" hreflang="fr" href="/fr/">
+					<link rel="alternate" hreflang="x-default" href="/">
+				</head>
+				<body>
+					<a href="/de/">Deutsch</a>
+					<a href="/redirect/">redirect</a>
+					<a href="/error/">error</a>
+					<a href="/noindex/">noindex</a>
+					<a href="/chain/">chain</a>
+					<a href="/other/">other</a>
+				</body>
+				</html>`
+
+	CanonicalFrench = `	<!DOCTYPE html>
+				<html lang="fr">
+				<head>
+					<title>Accueil</title>
+					<link rel="canonical" href="/fr/">
+					<link rel="alternate" hreflang="en" href="/">
+					<link rel="alternate" hreflang="fr" href="/fr/">
+					<link rel="alternate" hreflang="x-default" href="/">
+				</head>
+				<body>
+				</body>
+				</html>`
+
+	CanonicalGerman = `	<!DOCTYPE html>
+				<html lang="de">
+				<head>
+					<title>Startseite</title>
+					<link rel="alternate" hreflang="de" href="/de/">
+					<link rel="alternate" hreflang="en" href="/">
+				</head>
+				<body>
+				</body>
+				</html>`
+
+	CanonicalHidden = `	<!DOCTYPE html>
+				<head>
+					<title>Hidden</title>
+					<meta name="robots" content="noindex">
+					<link rel="canonical" href="/hidden/">
+				</head>
+				<body>
+				</body>
+				</html>`
+
+	// CanonicalPage is a page with the canonical url
+	// filled in with fmt
+	CanonicalPage = `	<!DOCTYPE html>
+				<head>
+					<title>Page</title>
+					<link rel="canonical" href="%s">
+				</head>
+				<body>
+				</body>
+				</html>`
+)
@@ -0,0 +1,47 @@
+package mock
+
+var (
+	DirectivesIndex = `	<!DOCTYPE html>
+				<head>
+					<title>Directives</title>
+				</head>
+				<body>
+					<a href="/follow/">follow</a>
+					<a rel="nofollow noopener" href="/nofollow/">nofollow</a>
+					<a href="/meta/">meta</a>
+					<a href="/header/">header</a>
+					<a href="/agent/">agent</a>
+					<a href="/agent-meta/">agent meta</a>
+				</body>
+				</html>`
+
+	DirectivesMeta = `	<!DOCTYPE html>
+				<head>
+					<title>Meta</title>
+					<meta name="Robots" content="NOINDEX, nofollow">
+				</head>
+				<body>
+					<a href="/meta/child/">child</a>
+				</body>
+				</html>`
+
+	DirectivesAgentMeta = `	<!DOCTYPE html>
+				<head>
+					<title>Agent Meta</title>
+					<meta name="test-robot" content="noindex">
+					<meta name="other-robot" content="nofollow">
+				</head>
+				<body>
+					<a href="/">home</a>
+				</body>
+				</html>`
+
+	DirectivesPage = `	<!DOCTYPE html>
+				<head>
+					<title>Page</title>
+				</head>
+				<body>
+					<a href="/">home</a>
+				</body>
+				</html>`
+)
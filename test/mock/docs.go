@@ -0,0 +1,42 @@
+package mock
+
+var (
+	DocsIndex = `	<!DOCTYPE html>
+				<head>
+					<title>Title</title>
+				</head>
+				<body>
+					<a href="/docs/">docs</a>
+					<a href="/blog/">blog</a>
+				</body>
+				</html>`
+
+	DocsPage = `	<!DOCTYPE html>
+				<head>
+					<title>Docs</title>
+				</head>
+				<body>
+					<a href="/">home</a>
+					<a href="/docs/1/">1</a>
+				</body>
+				</html>`
+
+	DocsPage1 = `	<!DOCTYPE html>
+				<head>
+					<title>Docs 1</title>
+				</head>
+				<body>
+					<a href="/docs/">docs</a>
+					<a href="/blog/">blog</a>
+				</body>
+				</html>`
+
+	BlogPage = `	<!DOCTYPE html>
+				<head>
+					<title>Blog</title>
+				</head>
+				<body>
+					<a href="/">home</a>
+				</body>
+				</html>`
+)
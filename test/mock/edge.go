@@ -0,0 +1,25 @@
+package mock
+
+var (
+	EdgeIndex = `	<!DOCTYPE html>
+				<head>
+					<title>Edges</title>
+				</head>
+				<body>
+					<header>
+						<nav><a href="/a/" title="Page A" rel="Nofollow noopener">Nav A</a></nav>
+					</header>
+					<main>
+						<a href="/b/" target="_blank">
+							Main
+							B
+						</a>
+						<a href="/b/">again</a>
+						<a href="/c/"><img src="/c.png" alt="C image"></a>
+						<aside><a href="/b/">aside</a></aside>
+					</main>
+					<footer><a href="/d/">Footer D</a></footer>
+					<a href="/e/">Body E</a>
+				</body>
+				</html>`
+)
@@ -0,0 +1,69 @@
+package mock
+
+var (
+	ExtractIndex = `	<!DOCTYPE html>
+				<head>
+					<title>Extract</title>
+					<meta http-equiv="refresh" content="5; URL='/refresh/'">
+					<link rel="next" href="/next/">
+					<link rel="canonical" href="/canonical/">
+					<link rel="alternate" hreflang="de" href="/alternate/">
+					<link rel="stylesheet" href="/style.css">
+					<script src="/app.js"></script>
+				</head>
+				<body>
+					<map name="map">
+						<area shape="rect" coords="0,0,10,10" href="/area/">
+					</map>
+					<iframe src="/frame/"></iframe>
+					<form action="/search/" method="get"></form>
+					<form action="/post/" method="POST"></form>
+					<img src="/image.png" srcset="/image-2x.png 2x, /image-3x.png 3x">
+					<img src="/missing.png">
+					<video src="/movie.mp4" poster="/image.png">
+						<source src="/movie.webm">
+					</video>
+				</body>
+				</html>`
+
+	// OutcomeIndex links to the same page as nofollow
+	// first, then twice as a link that's followed
+	OutcomeIndex = `	<!DOCTYPE html>
+				<head>
+					<title>Outcome</title>
+				</head>
+				<body>
+					<a rel="nofollow" href="/later/">nofollow</a>
+					<a href="/later/">later</a>
+					<a href="/later/">later again</a>
+					<a href="/page/">page</a>
+				</body>
+				</html>`
+
+	// OutcomePage is a page with an image and a link
+	OutcomePage = `	<!DOCTYPE html>
+				<head>
+					<title>Page</title>
+				</head>
+				<body>
+					<img src="/image.png">
+					<a href="/deeper/">deeper</a>
+				</body>
+				</html>`
+
+	// CDNIndex is a page with assets and a link on
+	// the cdn, its url filled in with fmt
+	CDNIndex = `	<!DOCTYPE html>
+				<head>
+					<title>CDN</title>
+					<link rel="stylesheet" href="%[1]s/style.css">
+				</head>
+				<body>
+					<img src="%[1]s/logo.png">
+					<img src="%[1]s/missing.png">
+					<img src="%[1]s/2/blocked.png">
+					<a href="%[1]s/page/">cdn page</a>
+					<a href="/1/">page</a>
+				</body>
+				</html>`
+)
@@ -0,0 +1,29 @@
+package mock
+
+var (
+	InfoIndex = `	<!DOCTYPE html>
+				<html lang="en-GB">
+				<head>
+					<title>  Info
+						Page </title>
+					<meta name="Description" content="The   page description">
+					<meta name="description" content="Second description">
+					<link rel="canonical" href="/canonical/#main">
+					<link rel="alternate" hreflang=" fr " href="/fr/">
+					<link rel="alternate" hreflang="x-default" href="/">
+					<link rel="alternate" type="application/rss+xml" href="/feed/">
+					<style>body { color: red; }</style>
+					<script>var words = "not counted";</script>
+				</head>
+				<body>
+					<h1>Main <em>heading</em></h1>
+					<p>Some words on the page.</p>
+					<h2>First</h2>
+					<h2>Second</h2>
+					<h3>Third</h3>
+					<img src="/a.png" alt="">
+					<img src="/b.png">
+					<img src="/c.png" />
+				</body>
+				</html>`
+)
@@ -38,3 +38,79 @@ var (
 							</body>
 							</html>`
 )
+
+var (
+	BaseHrefPage = `	<!DOCTYPE html>
+				<head>
+					<title>Title</title>
+					<base href="/static/">
+				</head>
+				<body>
+					<a href="page.html">page</a>
+					<a href="api/">api</a>
+					<a href="/absolute/">absolute</a>
+				</body>
+				</html>`
+
+	RelativePage = `	<!DOCTYPE html>
+				<head>
+					<title>Title</title>
+				</head>
+				<body>
+					<a href="sibling/">sibling</a>
+					<a href="../">up</a>
+				</body>
+				</html>`
+)
+
+var (
+	RedirectChainIndex = `	<!DOCTYPE html>
+				<head>
+					<title>Title</title>
+				</head>
+				<body>
+					<a href="/chain/">chain</a>
+					<a href="/loop/">loop</a>
+					<a href="/away/">away</a>
+				</body>
+				</html>`
+)
+
+var (
+	DepthIndex = `	<!DOCTYPE html>
+				<head>
+					<title>Title</title>
+				</head>
+				<body>
+					<a href="/slow/">slow</a>
+					<a href="/fast/">fast</a>
+				</body>
+				</html>`
+
+	DepthSlow = `	<!DOCTYPE html>
+				<head>
+					<title>Title</title>
+				</head>
+				<body>
+					<a href="/x/">x</a>
+				</body>
+				</html>`
+
+	DepthFast = `	<!DOCTYPE html>
+				<head>
+					<title>Title</title>
+				</head>
+				<body>
+					<a href="/fast/2/">2</a>
+				</body>
+				</html>`
+
+	DepthX = `	<!DOCTYPE html>
+				<head>
+					<title>Title</title>
+				</head>
+				<body>
+					<a href="/y/">y</a>
+				</body>
+				</html>`
+)
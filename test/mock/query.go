@@ -0,0 +1,25 @@
+package mock
+
+var (
+	QueryIndex = `	<!DOCTYPE html>
+				<head>
+					<title>Title</title>
+				</head>
+				<body>
+					<a href="/list?page=2">2</a>
+					<a href="/list?page=3">3</a>
+					<a href="/list?utm_source=home&page=2">2</a>
+					<a href="/About">about</a>
+					<a href="/about/#team">team</a>
+				</body>
+				</html>`
+
+	QueryList = `	<!DOCTYPE html>
+				<head>
+					<title>Title</title>
+				</head>
+				<body>
+					<a href="/">home</a>
+				</body>
+				</html>`
+)
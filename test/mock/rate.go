@@ -0,0 +1,62 @@
+package mock
+
+var (
+	RateIndex = `	<!DOCTYPE html>
+				<head>
+					<title>Title</title>
+				</head>
+				<body>
+					<a href="/1/">1</a>
+					<a href="/2/">2</a>
+					<a href="/3/">3</a>
+					<a href="/4/">4</a>
+					<a href="/5/">5</a>
+					<a href="/6/">6</a>
+				</body>
+				</html>`
+
+	RatePage = `	<!DOCTYPE html>
+				<head>
+					<title>Page</title>
+				</head>
+				<body>
+				</body>
+				</html>`
+
+	// SharedPage is a page linking to the same
+	// page as the others on its level
+	SharedPage = `	<!DOCTYPE html>
+				<head>
+					<title>Page</title>
+				</head>
+				<body>
+					<a href="/shared/">shared</a>
+				</body>
+				</html>`
+
+	RetryIndex = `	<!DOCTYPE html>
+				<head>
+					<title>Title</title>
+				</head>
+				<body>
+					<a href="/flaky/">flaky</a>
+					<a href="/down/">down</a>
+					<a href="/missing/">missing</a>
+					<a href="/limited/">limited</a>
+					<a href="/ok/">ok</a>
+				</body>
+				</html>`
+
+	RetryAssetIndex = `	<!DOCTYPE html>
+				<head>
+					<title>Title</title>
+				</head>
+				<body>
+					<img src="/flaky.png" alt="flaky">
+				</body>
+				</html>`
+
+	RobotsCrawlDelay = `User-agent: *
+Crawl-delay: 0.1
+Allow: /`
+)
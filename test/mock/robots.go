@@ -1,5 +1,6 @@
 package mock
 
 var (
-	RobotsDisallow = "User-Agent: *\r\nDisallow: /2/\r\n"
+	RobotsDisallow    = "User-Agent: *\r\nDisallow: /2/\r\n"
+	RobotsDisallowAll = "User-agent: *\nDisallow: /\n"
 )
@@ -0,0 +1,77 @@
+package mock
+
+var (
+	SitemapHome = `	<!DOCTYPE html>
+				<head>
+					<title>Title</title>
+				</head>
+				<body>
+					<a href="/linked/">linked</a>
+					<a href="/about/">about</a>
+				</body>
+				</html>`
+
+	SitemapLinked = `	<!DOCTYPE html>
+				<head>
+					<title>Linked</title>
+				</head>
+				<body>
+					<a href="/">home</a>
+				</body>
+				</html>`
+
+	SitemapUnlinked = `	<!DOCTYPE html>
+				<head>
+					<title>Unlinked</title>
+				</head>
+				<body>
+				</body>
+				</html>`
+
+	SitemapRobots = `User-agent: *
+Disallow: /private/
+Sitemap: %[1]s/sitemap_index.xml`
+
+	SitemapIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>%[1]s/sitemap-pages.xml</loc></sitemap>
+	<sitemap><loc>%[1]s/sitemap-posts.xml.gz</loc></sitemap>
+	<sitemap><loc>%[1]s/sitemap_index.xml</loc></sitemap>
+</sitemapindex>`
+
+	SitemapPages = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>%[1]s/</loc></url>
+	<url><loc>%[1]s/linked/</loc></url>
+	<url><loc>%[1]s/private/</loc></url>
+</urlset>`
+
+	SitemapPosts = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc> %[1]s/unlinked/ </loc></url>
+	<url><loc>%[1]s/moved/</loc></url>
+	<url><loc>%[1]s/gone/</loc></url>
+	<url><loc>http://other.example.com/</loc></url>
+</urlset>`
+
+	// SitemapDepthPage is a page linking to the
+	// next page down, its url filled in with fmt
+	SitemapDepthPage = `	<!DOCTYPE html>
+				<head>
+					<title>Depth</title>
+				</head>
+				<body>
+					<a href="%s">next</a>
+				</body>
+				</html>`
+
+	SitemapDepth = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>%[1]s/deep/</loc></url>
+</urlset>`
+
+	SitemapFallback = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>%[1]s/unlinked/</loc></url>
+</urlset>`
+)
@@ -0,0 +1,65 @@
+package mock
+
+var (
+	SoftNotFoundIndex = `	<!DOCTYPE html>
+				<head>
+					<title>Shop</title>
+				</head>
+				<body>
+					<a href="/products/">Products</a>
+					<a href="/discontinued/">Discontinued range</a>
+					<a href="/oops/">Oops</a>
+					<a href="/gone/">Gone</a>
+				</body>
+				</html>`
+
+	SoftNotFoundProducts = `	<!DOCTYPE html>
+				<head>
+					<title>Products</title>
+				</head>
+				<body>
+					<h1>Products</h1>
+					<p>Everything we sell, from shoes and socks to hats and scarves.</p>
+					<a href="/">home</a>
+				</body>
+				</html>`
+
+	SoftNotFoundOops = `	<!DOCTYPE html>
+				<head>
+					<title>Page Not Found | Shop</title>
+				</head>
+				<body>
+					<p>Oops.</p>
+				</body>
+				</html>`
+
+	SoftNotFoundRedirectIndex = `	<!DOCTYPE html>
+				<head>
+					<title>Shop</title>
+				</head>
+				<body>
+					<a href="/products/">Products</a>
+					<a href="/moved/">Moved</a>
+					<a href="/old/">Old range</a>
+					<a href="/not-found/">Help</a>
+				</body>
+				</html>`
+
+	// SoftNotFoundPage is the page served with a 200
+	// for every url that doesn't exist
+	SoftNotFoundPage = `	<!DOCTYPE html>
+				<head>
+					<title>Shop</title>
+				</head>
+				<body>
+					<h1>Sorry!</h1>
+					<p>We looked everywhere but we couldn't find what you were looking
+					for. It may have been moved, renamed or taken down, or the link you
+					followed may be out of date. Try searching the shop, browsing our
+					products from the menu, or heading back to the home page to start
+					again. If you think something is wrong, get in touch with our
+					customer service team and we'll be happy to help you find it.</p>
+					<a href="/">home</a>
+				</body>
+				</html>`
+)
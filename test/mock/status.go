@@ -0,0 +1,26 @@
+package mock
+
+var (
+	StatusIndex = `	<!DOCTYPE html>
+				<head>
+					<title>Title</title>
+				</head>
+				<body>
+					<a href="/404/">Missing
+						<b>page</b></a>
+					<a href="/500/">500</a>
+					<a href="/report.pdf">report</a>
+					<a href="/about/">about</a>
+				</body>
+				</html>`
+
+	StatusAbout = `	<!DOCTYPE html>
+				<head>
+					<title>About</title>
+				</head>
+				<body>
+					<a href="/404/">gone</a>
+					<a href="/">home</a>
+				</body>
+				</html>`
+)
@@ -1,11 +1,29 @@
 package test
 
 import (
+	"compress/gzip"
+	"crypto/tls"
 	"fmt"
 	"github.com/m1/smap/test/mock"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The credentials accepted by the protected server
+const (
+	AuthUser      = "user"
+	AuthPassword  = "secret"
+	AuthToken     = "token"
+	AuthHeader    = "X-Api-Key"
+	AuthHeaderKey = "key"
+	SessionCookie = "session"
+	SessionValue  = "valid"
 )
 
 type Server struct {
@@ -49,6 +67,124 @@ func NewRedirectServer() *Server {
 	return server
 }
 
+func NewDocsServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", testResponse(mock.DocsIndex))
+	server.HandleFunc("/docs/", testResponse(mock.DocsPage))
+	server.HandleFunc("/docs/1/", testResponse(mock.DocsPage1))
+	server.HandleFunc("/blog/", testResponse(mock.BlogPage))
+	return server
+}
+
+func NewBaseHrefServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/docs/guide/", testResponse(mock.BaseHrefPage))
+	server.HandleFunc("/old/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new/", http.StatusMovedPermanently)
+	})
+	server.HandleFunc("/new/", testResponse(mock.RelativePage))
+	return server
+}
+
+func NewQueryServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", testResponse(mock.QueryIndex))
+	server.HandleFunc("/list", testResponse(mock.QueryList))
+	server.HandleFunc("/about/", testResponse(mock.QueryList))
+	return server
+}
+
+func NewStatusServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Last-Modified", "Wed, 13 Mar 2019 10:00:00 GMT")
+		w.Header().Set("X-Unrecorded", "1")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, mock.StatusIndex)
+	})
+	server.HandleFunc("/about/", testResponse(mock.StatusAbout))
+	server.HandleFunc("/404/", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	server.HandleFunc("/500/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	server.HandleFunc("/report.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		fmt.Fprint(w, "%PDF-1.4")
+	})
+	return server
+}
+
+func NewSlowServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", testResponse(mock.OkIndex))
+	server.HandleFunc("/1/", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	return server
+}
+
+// NewDepthServer serves a page two clicks from the index that's
+// also three clicks away through pages that respond quicker
+func NewDepthServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", testResponse(mock.DepthIndex))
+	server.HandleFunc("/slow/", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		testResponse(mock.DepthSlow)(w, r)
+	})
+	server.HandleFunc("/fast/", testResponse(mock.DepthFast))
+	server.HandleFunc("/fast/2/", testResponse(mock.DepthSlow))
+	server.HandleFunc("/x/", testResponse(mock.DepthX))
+	server.HandleFunc("/y/", testResponse(mock.DirectivesPage))
+	return server
+}
+
+func NewRedirectChainServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", testResponse(mock.RedirectChainIndex))
+	server.HandleFunc("/chain/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/chain/1/", http.StatusMovedPermanently)
+	})
+	server.HandleFunc("/chain/1/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/chain/2/", http.StatusFound)
+	})
+	server.HandleFunc("/chain/2/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/chain/3/", http.StatusTemporaryRedirect)
+	})
+	server.HandleFunc("/chain/3/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final/", http.StatusPermanentRedirect)
+	})
+	server.HandleFunc("/final/", testResponse(mock.OkPage2Redirected))
+	server.HandleFunc("/loop/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop/2/", http.StatusFound)
+	})
+	server.HandleFunc("/loop/2/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop/", http.StatusFound)
+	})
+	server.HandleFunc("/away/", func(w http.ResponseWriter, r *http.Request) {
+		_, port, _ := net.SplitHostPort(r.Host)
+		http.Redirect(w, r, "http://localhost:"+port+"/final/", http.StatusMovedPermanently)
+	})
+	return server
+}
+
 func NewBufErrServer() *Server {
 	server := &Server{
 		ServeMux: http.NewServeMux(),
@@ -59,6 +195,465 @@ func NewBufErrServer() *Server {
 	return server
 }
 
+func NewSitemapServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", testResponse(mock.SitemapHome))
+	server.HandleFunc("/linked/", testResponse(mock.SitemapLinked))
+	server.HandleFunc("/unlinked/", testResponse(mock.SitemapUnlinked))
+	server.HandleFunc("/about/", testResponse(mock.SitemapUnlinked))
+	server.HandleFunc("/moved/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/linked/", http.StatusMovedPermanently)
+	})
+	server.HandleFunc("/gone/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	server.HandleFunc("/private/", testResponse(mock.SitemapUnlinked))
+	server.HandleFunc("/sitemap.xml", sitemapResponse(mock.SitemapFallback, false))
+	server.HandleFunc("/sitemap_index.xml", sitemapResponse(mock.SitemapIndex, false))
+	server.HandleFunc("/sitemap-pages.xml", sitemapResponse(mock.SitemapPages, false))
+	server.HandleFunc("/sitemap-posts.xml.gz", sitemapResponse(mock.SitemapPosts, true))
+	return server
+}
+
+// NewSitemapDepthServer serves two chains of pages, one
+// from the home page and one from the page in its sitemap
+func NewSitemapDepthServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", depthPage("/1/"))
+	server.HandleFunc("/1/", depthPage("/2/"))
+	server.HandleFunc("/2/", testResponse(mock.SitemapUnlinked))
+	server.HandleFunc("/deep/", depthPage("/deep/1/"))
+	server.HandleFunc("/deep/1/", depthPage("/deep/2/"))
+	server.HandleFunc("/deep/2/", testResponse(mock.SitemapUnlinked))
+	server.HandleFunc("/sitemap.xml", sitemapResponse(mock.SitemapDepth, false))
+	return server
+}
+
+// depthPage serves a page linking to the url
+func depthPage(next string) func(w http.ResponseWriter, r *http.Request) {
+	return testResponse(fmt.Sprintf(mock.SitemapDepthPage, next))
+}
+
+// NewDirectivesServer serves pages marked noindex and nofollow
+// by their meta robots tags, X-Robots-Tag headers and rel
+func NewDirectivesServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", testResponse(mock.DirectivesIndex))
+	server.HandleFunc("/follow/", testResponse(mock.DirectivesPage))
+	server.HandleFunc("/nofollow/", testResponse(mock.DirectivesPage))
+	server.HandleFunc("/meta/", testResponse(mock.DirectivesMeta))
+	server.HandleFunc("/meta/child/", testResponse(mock.DirectivesPage))
+	server.HandleFunc("/header/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Robots-Tag", "noindex")
+		testResponse(mock.DirectivesPage)(w, r)
+	})
+	server.HandleFunc("/agent/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Robots-Tag", "other-robot: noindex")
+		w.Header().Add("X-Robots-Tag", "test-robot: nofollow")
+		w.Header().Add("X-Robots-Tag", "unavailable_after: 25 Jun 2030 15:00:00 PST")
+		testResponse(mock.DirectivesPage)(w, r)
+	})
+	server.HandleFunc("/agent-meta/", testResponse(mock.DirectivesAgentMeta))
+	return server
+}
+
+// NewExtractServer serves a page linking to pages and assets
+// with each of the elements of the default link rules
+func NewExtractServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", testResponse(mock.ExtractIndex))
+	for _, path := range []string{"/refresh/", "/next/", "/canonical/", "/alternate/", "/area/", "/frame/", "/search/", "/post/"} {
+		server.HandleFunc(path, testResponse(mock.DirectivesPage))
+	}
+	for _, path := range []string{"/style.css", "/app.js", "/image.png", "/image-2x.png", "/image-3x.png", "/movie.mp4", "/movie.webm"} {
+		server.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			fmt.Fprint(w, "asset")
+		})
+	}
+	server.HandleFunc("/missing.png", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	return server
+}
+
+// NewLinkOutcomeServer serves a page linking to the same
+// page with different outcomes, and a page with an image
+func NewLinkOutcomeServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", testResponse(mock.OutcomeIndex))
+	server.HandleFunc("/page/", testResponse(mock.OutcomePage))
+	server.HandleFunc("/later/", testResponse(mock.DirectivesPage))
+	server.HandleFunc("/deeper/", testResponse(mock.DirectivesPage))
+	server.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		fmt.Fprint(w, "png")
+	})
+	return server
+}
+
+// NewCDNServer serves the assets linked to by the pages
+// of `NewCDNSiteServer`, along with a page that shouldn't
+// be crawled
+func NewCDNServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	for _, path := range []string{"/style.css", "/logo.png", "/2/blocked.png"} {
+		server.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			fmt.Fprint(w, "asset")
+		})
+	}
+	server.HandleFunc("/missing.png", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	server.HandleFunc("/page/", testResponse(mock.DirectivesPage))
+	server.WithOkayRobots()
+	return server
+}
+
+// NewCDNSiteServer serves a page with its assets on the cdn
+func NewCDNSiteServer(cdn string) *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", testResponse(fmt.Sprintf(mock.CDNIndex, cdn)))
+	server.HandleFunc("/1/", testResponse(mock.DirectivesPage))
+	return server
+}
+
+// NewEdgeServer serves a page with links in each region
+// of the page, with duplicate links and an image link
+func NewEdgeServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", testResponse(mock.EdgeIndex))
+	for _, path := range []string{"/a/", "/b/", "/c/", "/d/", "/e/"} {
+		server.HandleFunc(path, testResponse(mock.DirectivesPage))
+	}
+	return server
+}
+
+// NewInfoServer serves a page with each of the
+// on-page metadata recorded
+func NewInfoServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", testResponse(mock.InfoIndex))
+	return server
+}
+
+// NewCanonicalServer serves pages with canonicals pointing at
+// redirects, errors, noindex pages, other canonicals and other
+// hosts, and an hreflang set missing a return link
+func NewCanonicalServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", testResponse(mock.CanonicalIndex))
+	server.HandleFunc("/fr/", testResponse(mock.CanonicalFrench))
+	server.HandleFunc("/de/", testResponse(mock.CanonicalGerman))
+	server.HandleFunc("/hidden/", testResponse(mock.CanonicalHidden))
+	server.HandleFunc("/redirect/", canonicalResponse("/moved/"))
+	server.HandleFunc("/error/", canonicalResponse("/gone/"))
+	server.HandleFunc("/noindex/", canonicalResponse("/hidden/"))
+	server.HandleFunc("/chain/", canonicalResponse("/chain/2/"))
+	server.HandleFunc("/chain/2/", canonicalResponse("/chain/3/"))
+	server.HandleFunc("/chain/3/", canonicalResponse("/chain/3/"))
+	server.HandleFunc("/other/", canonicalResponse("https://other.example.com/other/"))
+	server.HandleFunc("/moved/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/", http.StatusMovedPermanently)
+	})
+	server.HandleFunc("/gone/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	return server
+}
+
+// canonicalResponse serves a page with the canonical url
+func canonicalResponse(canonical string) func(w http.ResponseWriter, r *http.Request) {
+	return testResponse(fmt.Sprintf(mock.CanonicalPage, canonical))
+}
+
+// NewSoftNotFoundServer serves its not found page with a 200
+// for every url that doesn't exist, along with a page that has
+// a not found title and a real 404
+func NewSoftNotFoundServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			testResponse(mock.SoftNotFoundIndex)(w, r)
+			return
+		}
+		testResponse(mock.SoftNotFoundPage)(w, r)
+	})
+	server.HandleFunc("/products/", testResponse(mock.SoftNotFoundProducts))
+	server.HandleFunc("/oops/", testResponse(mock.SoftNotFoundOops))
+	server.HandleFunc("/gone/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	return server
+}
+
+// NewSoftNotFoundRedirectServer redirects every url that
+// doesn't exist to its not found page, along with a page
+// that redirects to a page that does exist
+func NewSoftNotFoundRedirectServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			testResponse(mock.SoftNotFoundRedirectIndex)(w, r)
+			return
+		}
+		http.Redirect(w, r, "/not-found/", http.StatusFound)
+	})
+	server.HandleFunc("/not-found/", testResponse(mock.SoftNotFoundPage))
+	server.HandleFunc("/products/", testResponse(mock.SoftNotFoundProducts))
+	server.Handle("/moved/", http.RedirectHandler("/products/", http.StatusMovedPermanently))
+	return server
+}
+
+// sitemapResponse serves the sitemap with the
+// urls in it pointing at the server
+func sitemapResponse(sitemap string, gz bool) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := fmt.Sprintf(sitemap, "http://"+r.Host)
+		w.WriteHeader(http.StatusOK)
+		if !gz {
+			fmt.Fprint(w, body)
+			return
+		}
+
+		gzw := gzip.NewWriter(w)
+		fmt.Fprint(gzw, body)
+		gzw.Close()
+	}
+}
+
+// RequestLog records when each request to a server was made
+// and the most requests that were in flight at once
+type RequestLog struct {
+	mu        sync.Mutex
+	times     []time.Time
+	paths     []string
+	remotes   map[string]bool
+	active    int
+	maxActive int
+}
+
+// Times passes back when each request was made
+func (l *RequestLog) Times() []time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]time.Time{}, l.times...)
+}
+
+// Paths passes back the path of each request
+func (l *RequestLog) Paths() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string{}, l.paths...)
+}
+
+// Remotes passes back the number of different client
+// addresses the requests came from, i.e. connections
+func (l *RequestLog) Remotes() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.remotes)
+}
+
+// MaxActive passes back the most requests
+// that were in flight at once
+func (l *RequestLog) MaxActive() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.maxActive
+}
+
+func (l *RequestLog) start(r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.times = append(l.times, time.Now())
+	l.paths = append(l.paths, r.URL.Path)
+	if l.remotes == nil {
+		l.remotes = make(map[string]bool)
+	}
+	l.remotes[r.RemoteAddr] = true
+	l.active++
+	if l.active > l.maxActive {
+		l.maxActive = l.active
+	}
+}
+
+func (l *RequestLog) end() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+}
+
+// logged records the requests to the handler in the log,
+// responding after the latency
+func (l *RequestLog) logged(latency time.Duration, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l.start(r)
+		defer l.end()
+		time.Sleep(latency)
+		handler(w, r)
+	}
+}
+
+func NewRateServer(log *RequestLog, latency time.Duration) *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", log.logged(latency, testResponse(mock.RateIndex)))
+	for i := 1; i <= 6; i++ {
+		server.HandleFunc(fmt.Sprintf("/%d/", i), log.logged(latency, testResponse(mock.RatePage)))
+	}
+	return server
+}
+
+// NewSharedLinkServer serves pages that all
+// link to the same page
+func NewSharedLinkServer(log *RequestLog) *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", log.logged(0, testResponse(mock.RateIndex)))
+	for i := 1; i <= 6; i++ {
+		server.HandleFunc(fmt.Sprintf("/%d/", i), log.logged(0, testResponse(mock.SharedPage)))
+	}
+	server.HandleFunc("/shared/", log.logged(0, testResponse(mock.RatePage)))
+	return server
+}
+
+func NewThrottleServer(log *RequestLog) *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", log.logged(100*time.Millisecond, testResponse(mock.RateIndex)))
+	server.HandleFunc("/1/", log.logged(0, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	for i := 2; i <= 6; i++ {
+		server.HandleFunc(fmt.Sprintf("/%d/", i), log.logged(100*time.Millisecond, testResponse(mock.RatePage)))
+	}
+	return server
+}
+
+func NewRetryServer(log *RequestLog) *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+
+	var flaky, limited int32
+	server.HandleFunc("/", log.logged(0, testResponse(mock.RetryIndex)))
+	server.HandleFunc("/flaky/", log.logged(0, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&flaky, 1) <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		testResponse(mock.RatePage)(w, r)
+	}))
+	server.HandleFunc("/down/", log.logged(0, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	server.HandleFunc("/missing/", log.logged(0, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	server.HandleFunc("/limited/", log.logged(0, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&limited, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		testResponse(mock.RatePage)(w, r)
+	}))
+	server.HandleFunc("/ok/", log.logged(0, testResponse(mock.RatePage)))
+	return server
+}
+
+// NewRetryAssetServer serves a page with an image
+// that fails the first time it's requested
+func NewRetryAssetServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+
+	var flaky int32
+	server.HandleFunc("/", testResponse(mock.RetryAssetIndex))
+	server.HandleFunc("/flaky.png", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&flaky, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		fmt.Fprint(w, "png")
+	})
+	return server
+}
+
+// NewProtectedServer serves the pages of `NewServer` only
+// to requests with basic auth, a bearer token, the api key
+// header or the session cookie set by posting to `/login`
+func NewProtectedServer() *Server {
+	server := &Server{
+		ServeMux: http.NewServeMux(),
+	}
+	server.HandleFunc("/", protected(testResponse(mock.OkIndex)))
+	server.HandleFunc("/1/", protected(testResponse(mock.OkPage1)))
+	server.HandleFunc("/2/", protected(testResponse(mock.OkPage2)))
+	server.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost ||
+			r.PostFormValue("username") != AuthUser ||
+			r.PostFormValue("password") != AuthPassword {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{Name: SessionCookie, Value: SessionValue, Path: "/"})
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+	return server
+}
+
+// protected only serves the handler to
+// requests with one of the credentials
+func protected(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		cookie, err := r.Cookie(SessionCookie)
+		switch {
+		case ok && user == AuthUser && password == AuthPassword,
+			r.Header.Get("Authorization") == "Bearer "+AuthToken,
+			r.Header.Get(AuthHeader) == AuthHeaderKey,
+			err == nil && cookie.Value == SessionValue:
+			handler(w, r)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}
+}
+
 func testResponse(page string) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -75,6 +670,83 @@ func (s *Server) WithOkayRobots() {
 	})
 }
 
+// WithRobotsStatus serves the robots.txt with the status
+func (s *Server) WithRobotsStatus(status int) {
+	s.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprint(w, mock.RobotsDisallowAll)
+	})
+}
+
+// WithRedirectedRobots redirects the robots.txt the number
+// of times before serving it
+func (s *Server) WithRedirectedRobots(redirects int) {
+	for i := 0; i < redirects; i++ {
+		from := fmt.Sprintf("/robots/%d.txt", i)
+		if i == 0 {
+			from = "/robots.txt"
+		}
+		to := fmt.Sprintf("/robots/%d.txt", i+1)
+		s.Handle(from, http.RedirectHandler(to, http.StatusMovedPermanently))
+	}
+
+	s.HandleFunc(fmt.Sprintf("/robots/%d.txt", redirects), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, mock.RobotsDisallow)
+	})
+}
+
+// WithLargeRobots serves a robots.txt with a disallow
+// rule past the first 500 KiB
+func (s *Server) WithLargeRobots() {
+	s.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, mock.RobotsDisallow)
+		fmt.Fprintf(w, "# %s\n", strings.Repeat("x", 500*1024))
+		fmt.Fprint(w, "Disallow: /1/\n")
+	})
+}
+
+func (s *Server) WithSitemapRobots() {
+	s.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		fmt.Fprintf(w, mock.SitemapRobots, "http://"+r.Host)
+	})
+}
+
+func (s *Server) WithCrawlDelayRobots() {
+	s.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		fmt.Fprint(w, mock.RobotsCrawlDelay)
+	})
+}
+
+func (s *Server) StartTLS() *Server {
+	s.Server = httptest.NewTLSServer(s.ServeMux)
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		panic(err)
+	}
+
+	s.Url = u
+	return s
+}
+
+// StartMutualTLS starts the server with tls, requiring
+// the clients to send a certificate
+func (s *Server) StartMutualTLS() *Server {
+	s.Server = httptest.NewUnstartedServer(s.ServeMux)
+	s.Server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	s.Server.StartTLS()
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		panic(err)
+	}
+
+	s.Url = u
+	return s
+}
+
 func (s *Server) Start() *Server {
 	s.Server = httptest.NewServer(s.ServeMux)
 
@@ -1,8 +1,12 @@
 package worker
 
+import (
+	"context"
+)
+
 // Job is the interface for the jobs that the workers
 // can execute. Once a worker selects a job, it runs
-// the function `Run`
+// the function `Run` with the pool's context
 type Job interface {
-	Run()
+	Run(ctx context.Context)
 }
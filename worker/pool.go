@@ -1,14 +1,27 @@
 package worker
 
 import (
+	"context"
 	"sync"
 )
 
 // Pool defines a pool of workers, with a max size
-// and stores a jobs queue
+// and stores a jobs queue. The size can be changed
+// while the pool is running
 type Pool struct {
-	size int
-	jobs chan Job
+	mu      sync.Mutex
+	size    int
+	started bool
+	jobs    chan Job
+	ctx     context.Context
+
+	// quit is sent on to stop a worker once it's
+	// finished its job, when shrinking the pool
+	quit chan struct{}
+
+	// closed is closed along with the jobs queue
+	// so that pending quits are dropped
+	closed chan struct{}
 
 	workersWg *sync.WaitGroup
 	jobsWg    *sync.WaitGroup
@@ -19,6 +32,9 @@ func NewPool(size int) *Pool {
 	pool := &Pool{
 		size:      size,
 		jobs:      make(chan Job),
+		ctx:       context.Background(),
+		quit:      make(chan struct{}),
+		closed:    make(chan struct{}),
 		workersWg: &sync.WaitGroup{},
 		jobsWg:    &sync.WaitGroup{},
 	}
@@ -28,29 +44,87 @@ func NewPool(size int) *Pool {
 
 // Start creates the workers and running their jobs
 func (p *Pool) Start() {
+	p.StartContext(context.Background())
+}
+
+// StartContext creates the workers and running their jobs,
+// once the context is done the workers stop picking up
+// jobs and the context is passed on to the running jobs
+func (p *Pool) StartContext(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.ctx = ctx
+	p.started = true
 	for i := 0; i < p.size; i++ {
-		p.workersWg.Add(1)
-		w := worker{
-			id:   i,
-			wg:   p.workersWg,
-			jobs: p.jobs,
-		}
+		p.spawn(i)
+	}
+}
 
-		go w.run()
+// spawn starts a new worker
+func (p *Pool) spawn(id int) {
+	p.workersWg.Add(1)
+	w := worker{
+		id:   id,
+		wg:   p.workersWg,
+		jobs: p.jobs,
+		quit: p.quit,
 	}
 
-	go func() {
-		p.workersWg.Wait()
-	}()
+	go w.run(p.ctx)
+}
+
+// Size passes back the number of workers
+// the pool is running with
+func (p *Pool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+// Resize changes the number of workers, new workers are
+// started straight away while the workers being stopped
+// finish the job they're running first. The pool always
+// keeps at least one worker
+func (p *Pool) Resize(size int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if size < 1 {
+		size = 1
+	}
+
+	if !p.started {
+		p.size = size
+		return
+	}
+
+	for ; p.size < size; p.size++ {
+		p.spawn(p.size)
+	}
+
+	for ; p.size > size; p.size-- {
+		go func() {
+			select {
+			case p.quit <- struct{}{}:
+			case <-p.closed:
+			case <-p.ctx.Done():
+			}
+		}()
+	}
 }
 
 // AddJob adds a job to the queue for the worker pool
-// to consume
+// to consume, the job is dropped if the pool's context
+// is done before a worker picks it up
 func (p *Pool) AddJob(job Job) {
 	p.jobsWg.Add(1)
 	go func() {
-		p.jobs <- job
-		p.jobsWg.Done()
+		defer p.jobsWg.Done()
+		select {
+		case p.jobs <- job:
+		case <-p.ctx.Done():
+		}
 	}()
 }
 
@@ -58,5 +132,7 @@ func (p *Pool) AddJob(job Job) {
 // to close the jobs queue and waits for the workers to finish
 func (p *Pool) Close() {
 	p.jobsWg.Wait()
+	close(p.closed)
 	close(p.jobs)
+	p.workersWg.Wait()
 }
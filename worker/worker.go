@@ -1,6 +1,7 @@
 package worker
 
 import (
+	"context"
 	"sync"
 )
 
@@ -8,11 +9,30 @@ type worker struct {
 	id   int
 	wg   *sync.WaitGroup
 	jobs chan Job
+	quit chan struct{}
 }
 
-func (w *worker) run() {
+func (w *worker) run(ctx context.Context) {
 	defer w.wg.Done()
-	for job := range w.jobs {
-		job.Run()
+	for {
+		// a pending quit is taken before any more jobs
+		// so the pool shrinks as soon as it can
+		select {
+		case <-w.quit:
+			return
+		default:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.quit:
+			return
+		case job, ok := <-w.jobs:
+			if !ok {
+				return
+			}
+			job.Run(ctx)
+		}
 	}
 }